@@ -0,0 +1,80 @@
+package apierr
+
+import (
+	"maps"
+)
+
+// Error — ошибка API со стабильным кодом. Клиенты сравнивают Code, а не
+// Message: текст может меняться, код — нет.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	// Fields — ошибки по отдельным полям запроса (для validation_failed).
+	Fields map[string]string
+	// Details — дополнительные данные, которые нужны клиенту для реакции на ошибку.
+	Details map[string]any
+
+	cause error
+}
+
+var catalog []*Error
+
+// define регистрирует код в каталоге; вызывается только при инициализации пакета.
+func define(code string, status int, message string) *Error {
+	e := &Error{Code: code, Status: status, Message: message}
+	catalog = append(catalog, e)
+	return e
+}
+
+// Catalog возвращает все объявленные коды в порядке объявления.
+func Catalog() []*Error {
+	return append([]*Error(nil), catalog...)
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Code + ": " + e.cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is сравнивает ошибки по коду, поэтому errors.Is(err, apierr.UserNotFound)
+// работает и для копий с полями или причиной.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap возвращает копию ошибки с внутренней причиной. Причина попадает
+// только в лог, клиенту она не отдаётся.
+func (e *Error) Wrap(cause error) *Error {
+	c := e.clone()
+	c.cause = cause
+	return c
+}
+
+func (e *Error) WithFields(fields map[string]string) *Error {
+	c := e.clone()
+	c.Fields = fields
+	return c
+}
+
+func (e *Error) WithDetail(key string, value any) *Error {
+	c := e.clone()
+	c.Details = maps.Clone(c.Details)
+	if c.Details == nil {
+		c.Details = map[string]any{}
+	}
+	c.Details[key] = value
+	return c
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	return &c
+}
@@ -0,0 +1,80 @@
+package apierr
+
+import "net/http"
+
+// Каталог кодов ошибок. Описание каждого кода — в docs/errors.md;
+// при добавлении нового кода обновите и его.
+
+// Общие
+var (
+	Internal         = define("internal_error", http.StatusInternalServerError, "Внутренняя ошибка сервера")
+	RouteNotFound    = define("route_not_found", http.StatusNotFound, "Маршрут не найден")
+	MethodNotAllowed = define("method_not_allowed", http.StatusMethodNotAllowed, "Метод не поддерживается")
+	InvalidJSON      = define("invalid_json", http.StatusBadRequest, "Неверный JSON")
+	ValidationFailed = define("validation_failed", http.StatusBadRequest, "Некорректные данные")
+	InvalidID        = define("invalid_id", http.StatusBadRequest, "Некорректный ID")
+	InvalidQuery     = define("invalid_query", http.StatusBadRequest, "Некорректный параметр запроса")
+	InvalidCursor    = define("invalid_cursor", http.StatusBadRequest, "Некорректный курсор")
+	RateLimited      = define("rate_limited", http.StatusTooManyRequests, "Слишком много запросов, попробуйте позже")
+)
+
+// Аутентификация и права
+var (
+	TokenRequired        = define("token_required", http.StatusUnauthorized, "Требуется access токен")
+	InvalidToken         = define("invalid_token", http.StatusUnauthorized, "Недействительный токен")
+	RefreshTokenRequired = define("refresh_token_required", http.StatusUnauthorized, "Нет refresh токена")
+	InvalidRefreshToken  = define("invalid_refresh_token", http.StatusUnauthorized, "Недействительный refresh токен")
+	InvalidCredentials   = define("invalid_credentials", http.StatusUnauthorized, "Неверный email или пароль")
+	AccountNotFound      = define("account_not_found", http.StatusUnauthorized, "Пользователь не найден")
+	EditForbidden        = define("edit_forbidden", http.StatusForbidden, "Нет прав на редактирование")
+	AdminRequired        = define("admin_required", http.StatusForbidden, "Требуются права администратора")
+	AccountSuspended     = define("account_suspended", http.StatusForbidden, "Аккаунт временно заблокирован")
+	AccountBanned        = define("account_banned", http.StatusForbidden, "Аккаунт заблокирован")
+)
+
+// Пользователи
+var (
+	UserNotFound        = define("user_not_found", http.StatusNotFound, "Пользователь не найден")
+	EmailTaken          = define("email_taken", http.StatusConflict, "Email уже используется")
+	UserAlreadyActive   = define("user_already_active", http.StatusBadRequest, "Пользователь уже активен")
+	UnsupportedLanguage = define("unsupported_language", http.StatusBadRequest, "Язык не поддерживается")
+	CannotModerateSelf  = define("cannot_moderate_self", http.StatusBadRequest, "Это действие нельзя применить к своему аккаунту")
+	UserNotBlocked      = define("user_not_blocked", http.StatusBadRequest, "Пользователь не заблокирован")
+	ModerationConflict  = define("moderation_conflict", http.StatusConflict, "Роль или блокировку пользователя уже изменили, обновите данные")
+	UserErased          = define("user_erased", http.StatusConflict, "Данные пользователя уже стёрты")
+	ExportNotFound      = define("export_not_found", http.StatusNotFound, "Выгрузка данных не найдена")
+)
+
+// Файлы и изображения
+var (
+	FileRequired     = define("file_required", http.StatusBadRequest, "Файл не найден")
+	FileUnreadable   = define("file_unreadable", http.StatusBadRequest, "Не удалось прочитать файл")
+	FileTooLarge     = define("file_too_large", http.StatusRequestEntityTooLarge, "Файл слишком большой")
+	NotAnImage       = define("not_an_image", http.StatusUnsupportedMediaType, "Файл не является изображением")
+	ImageCorrupted   = define("image_corrupted", http.StatusUnsupportedMediaType, "Не удалось прочитать изображение")
+	ImageTooLarge    = define("image_too_large", http.StatusRequestEntityTooLarge, "Слишком большое изображение")
+	FileNotFound     = define("file_not_found", http.StatusNotFound, "Файл не найден")
+	InvalidSignature = define("invalid_signature", http.StatusForbidden, "Неверная подпись ссылки")
+	LinkExpired      = define("link_expired", http.StatusForbidden, "Срок действия ссылки истёк")
+	AvatarNotFound   = define("avatar_not_found", http.StatusNotFound, "Аватар не найден")
+	InvalidSize      = define("invalid_avatar_size", http.StatusBadRequest, "Недопустимый размер")
+)
+
+// Медиатека и загрузка по частям
+var (
+	MediaTooLarge        = define("media_too_large", http.StatusRequestEntityTooLarge, "Файл слишком большой, используйте загрузку по частям")
+	MediaTypeNotAllowed  = define("media_type_not_allowed", http.StatusUnsupportedMediaType, "Файлы такого типа загружать нельзя")
+	QuotaExceeded        = define("quota_exceeded", http.StatusRequestEntityTooLarge, "Превышена квота хранилища")
+	MediaNotFound        = define("media_not_found", http.StatusNotFound, "Файл не найден")
+	MediaInUse           = define("media_in_use", http.StatusConflict, "Файл используется в опубликованных постах")
+	UploadNotFound       = define("upload_not_found", http.StatusNotFound, "Загрузка не найдена")
+	InvalidUploadOffset  = define("invalid_upload_offset", http.StatusBadRequest, "Некорректный заголовок Upload-Offset")
+	UploadOffsetMismatch = define("upload_offset_mismatch", http.StatusConflict, "Смещение не совпадает с полученным объёмом")
+	UploadConflict       = define("upload_conflict", http.StatusConflict, "Часть файла уже загружается параллельно")
+	BodyUnreadable       = define("body_unreadable", http.StatusBadRequest, "Не удалось прочитать данные")
+	ChunkEmpty           = define("chunk_empty", http.StatusBadRequest, "Пустая часть файла")
+	ChunkTooLarge        = define("chunk_too_large", http.StatusRequestEntityTooLarge, "Часть файла слишком большая")
+	UploadSizeExceeded   = define("upload_size_exceeded", http.StatusBadRequest, "Данных больше, чем заявлено при создании загрузки")
+	UploadIncomplete     = define("upload_incomplete", http.StatusBadRequest, "Файл загружен не полностью")
+	UploadTooLarge       = define("upload_too_large", http.StatusRequestEntityTooLarge, "Файл больше допустимого размера")
+)
@@ -0,0 +1,240 @@
+// Package audit связывает записи журнала аудита в цепочку: каждая хранит
+// хеш предыдущей и свой хеш от содержимого и предыдущего хеша. Правка или
+// удаление записи в базе в обход приложения обнаруживается Verify.
+package audit
+
+import (
+	"Blog/config"
+	"Blog/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"hash"
+	"time"
+)
+
+// chainLock — ключ advisory lock, под которым записи встают в цепочку по одной.
+const chainLock = 0x61756469
+
+var (
+	hmacKey []byte
+	async   bool
+)
+
+// Configure задаёт ключ HMAC и режим записи. Без ключа хеши — обычный
+// SHA-256: такую цепочку может пересчитать любой, у кого есть доступ на
+// запись в базу.
+func Configure(cfg config.Audit) {
+	hmacKey = []byte(cfg.HMACKey)
+	async = cfg.Mode == "async"
+	batchSize = cfg.BatchSize
+	flushInterval = cfg.FlushInterval.Duration
+}
+
+// Record записывает действие в транзакции db того изменения, которое оно
+// описывает: откат изменения откатывает и запись. В синхронном режиме запись
+// сразу встаёт в цепочку, в асинхронном — попадает в outbox, откуда её
+// переносит RunOutbox.
+func Record(ctx context.Context, db *gorm.DB, entry *models.AuditLog) error {
+	normalize(entry)
+	if async {
+		return enqueue(ctx, db, *entry)
+	}
+	return Append(ctx, db, entry)
+}
+
+// Append дописывает запись в конец цепочки.
+func Append(ctx context.Context, db *gorm.DB, entry *models.AuditLog) error {
+	normalize(entry)
+	entries := []models.AuditLog{*entry}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return appendLocked(tx, entries)
+	})
+	*entry = entries[0]
+	return err
+}
+
+// appendLocked дописывает записи в цепочку по порядку. Вставки
+// сериализуются через advisory lock до конца транзакции tx, поэтому порядок
+// id совпадает с порядком цепочки.
+func appendLocked(tx *gorm.DB, entries []models.AuditLog) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLock).Error; err != nil {
+		return err
+	}
+
+	var last []models.AuditLog
+	err := tx.Select("hash", "timestamp").Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	var prevHash string
+	var prevTime time.Time
+	if len(last) > 0 {
+		prevHash, prevTime = last[0].Hash, last[0].Timestamp
+	}
+	for i := range entries {
+		// время в цепочке не идёт назад, иначе соседние записи могли бы
+		// оказаться в разных месячных секциях не по порядку и архивирование
+		// вырезало бы кусок из середины цепочки
+		if entries[i].Timestamp.Before(prevTime) {
+			entries[i].Timestamp = prevTime.UTC()
+		}
+		entries[i].PrevHash = prevHash
+		entries[i].Hash = Hash(entries[i])
+		prevHash, prevTime = entries[i].Hash, entries[i].Timestamp
+	}
+
+	return tx.Create(&entries).Error
+}
+
+func normalize(entry *models.AuditLog) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	// Postgres хранит микросекунды: хешируем то, что потом прочитаем
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Microsecond)
+	entry.Metadata = canonical(entry.Metadata)
+}
+
+// Hash считает хеш записи от её содержимого и PrevHash. ID не входит:
+// он неизвестен до вставки, а порядок и так задаёт PrevHash.
+func Hash(entry models.AuditLog) string {
+	content, _ := json.Marshal([]any{
+		entry.UserID,
+		entry.Action,
+		entry.Object,
+		entry.ObjectID,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		entry.IP,
+		entry.UserAgent,
+		// записи до перехода на JSONB хранят metadata строкой JSON и
+		// хешируются так же, как раньше
+		canonical(entry.Metadata),
+	})
+
+	var h hash.Hash
+	if len(hmacKey) > 0 {
+		h = hmac.New(sha256.New, hmacKey)
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(entry.PrevHash))
+	h.Write([]byte{'\n'})
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Причины разрыва цепочки.
+const (
+	// запись изменена: хеш не сходится с содержимым
+	HashMismatch = "hash_mismatch"
+	// перед записью что-то удалено или вставлено: prev_hash не равен хешу предыдущей
+	PrevHashMismatch = "prev_hash_mismatch"
+	// у стёртой записи redacted_hash не тот, что в событии redact_entries
+	RedactionUnrecorded = "redaction_unrecorded"
+)
+
+// ChainBreak — первое место, где цепочка не сходится.
+type ChainBreak struct {
+	ID       uint   `json:"id"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// ChainReport — результат проверки. Удаление записей с конца цепочки проверка
+// не видит; для этого LastID и LastHash стоит сверять с ранее сохранёнными.
+type ChainReport struct {
+	Checked   int `json:"checked"`
+	Unchained int `json:"unchained"` // записи до появления цепочки
+	Redacted  int `json:"redacted"`  // записи со стёртыми личными данными, см. RedactUser
+	// хеш последней заархивированной записи: с него начинается цепочка в базе
+	Anchor   string      `json:"anchor,omitempty"`
+	LastID   uint        `json:"last_id"`
+	LastHash string      `json:"last_hash"`
+	Break    *ChainBreak `json:"break"`
+
+	started    bool            // встретилась первая запись цепочки
+	redactions map[uint]string // redacted_hash из событий redact_entries
+}
+
+func (r ChainReport) Valid() bool {
+	return r.Break == nil
+}
+
+// add сверяет очередную по id запись с цепочкой и учитывает её в отчёте.
+// Возвращает false на первом разрыве, он записывается в Break.
+func (r *ChainReport) add(entry models.AuditLog) bool {
+	if !r.started && entry.Hash == "" {
+		r.Unchained++
+		return true
+	}
+	r.started = true
+
+	if entry.PrevHash != r.LastHash {
+		r.Break = &ChainBreak{ID: entry.ID, Reason: PrevHashMismatch, Expected: r.LastHash, Actual: entry.PrevHash}
+		return false
+	}
+	// у записи со стёртыми данными содержимое сверяется с redacted_hash,
+	// а дальше цепочка продолжается от исходного hash
+	expected := entry.Hash
+	if entry.RedactedHash != "" {
+		if recorded := r.redactions[entry.ID]; recorded != entry.RedactedHash {
+			r.Break = &ChainBreak{ID: entry.ID, Reason: RedactionUnrecorded, Expected: recorded, Actual: entry.RedactedHash}
+			return false
+		}
+		expected = entry.RedactedHash
+		r.Redacted++
+	}
+	if h := Hash(entry); h != expected {
+		r.Break = &ChainBreak{ID: entry.ID, Reason: HashMismatch, Expected: h, Actual: expected}
+		return false
+	}
+
+	r.Checked++
+	r.LastID = entry.ID
+	r.LastHash = entry.Hash
+	return true
+}
+
+var errStop = errors.New("stop")
+
+// Verify проходит журнал по возрастанию id и останавливается на первом разрыве.
+func Verify(ctx context.Context, db *gorm.DB) (ChainReport, error) {
+	var report ChainReport
+
+	var archives []models.AuditArchive
+	if err := db.WithContext(ctx).Order("last_id DESC").Limit(1).Find(&archives).Error; err != nil {
+		return report, err
+	}
+	if len(archives) > 0 {
+		report.Anchor = archives[0].LastHash
+		report.LastHash = report.Anchor
+	}
+
+	// события о стирании идут в цепочке после стёртых записей, поэтому
+	// читаются заранее; подлинность их самих проверяется обходом ниже
+	var events []models.AuditLog
+	if err := db.WithContext(ctx).Select("id", "action", "metadata").Where("action = ?", RedactAction).Order("id").Find(&events).Error; err != nil {
+		return report, err
+	}
+	report.redactions = recordedRedactions(events)
+
+	var batch []models.AuditLog
+	err := db.WithContext(ctx).FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			if !report.add(entry) {
+				return errStop
+			}
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errStop) {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"Blog/models"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testChain строит цепочку так же, как appendLocked, но без базы.
+func testChain(entries ...models.AuditLog) []models.AuditLog {
+	prevHash := ""
+	for i := range entries {
+		entries[i].ID = uint(i + 1)
+		normalize(&entries[i])
+		entries[i].PrevHash = prevHash
+		entries[i].Hash = Hash(entries[i])
+		prevHash = entries[i].Hash
+	}
+	return entries
+}
+
+func verifyChain(entries []models.AuditLog) ChainReport {
+	report := ChainReport{redactions: recordedRedactions(entries)}
+	for _, entry := range entries {
+		if !report.add(entry) {
+			break
+		}
+	}
+	return report
+}
+
+func sampleEntries() []models.AuditLog {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	return testChain(
+		models.AuditLog{UserID: 1, Action: "login", Object: "user", ObjectID: 1, Timestamp: ts, IP: "10.0.0.1", UserAgent: "curl"},
+		models.AuditLog{UserID: 1, Action: "update_user", Object: "user", ObjectID: 1, Timestamp: ts.Add(time.Second),
+			Metadata: Metadata{Changes: map[string]Change{"email": {Before: "a@example.com", After: "b@example.com"}}}.JSON()},
+		models.AuditLog{UserID: 2, Action: "suspend_user", Object: "user", ObjectID: 1, Timestamp: ts.Add(2 * time.Second)},
+	)
+}
+
+func TestHash(t *testing.T) {
+	entry := sampleEntries()[0]
+	h := Hash(entry)
+	if len(h) != 64 || Hash(entry) != h {
+		t.Fatalf("хеш не детерминирован: %s", h)
+	}
+
+	// порядок ключей metadata на хеш не влияет
+	a, b := entry, entry
+	a.Metadata = json.RawMessage(`{"details":{"a":1,"b":2}}`)
+	b.Metadata = json.RawMessage(`{"details":{"b":2,"a":1}}`)
+	if Hash(a) != Hash(b) {
+		t.Error("хеш зависит от порядка ключей metadata")
+	}
+
+	changes := map[string]func(*models.AuditLog){
+		"user_id":   func(e *models.AuditLog) { e.UserID++ },
+		"action":    func(e *models.AuditLog) { e.Action = "logout" },
+		"object_id": func(e *models.AuditLog) { e.ObjectID++ },
+		"timestamp": func(e *models.AuditLog) { e.Timestamp = e.Timestamp.Add(time.Microsecond) },
+		"ip":        func(e *models.AuditLog) { e.IP = "10.0.0.2" },
+		"metadata":  func(e *models.AuditLog) { e.Metadata = json.RawMessage(`{"details":{"x":1}}`) },
+		"prev_hash": func(e *models.AuditLog) { e.PrevHash = "00" },
+	}
+	for name, change := range changes {
+		changed := entry
+		change(&changed)
+		if Hash(changed) == h {
+			t.Errorf("изменение %s не меняет хеш", name)
+		}
+	}
+
+	hmacKey = []byte("secret")
+	defer func() { hmacKey = nil }()
+	if Hash(entry) == h {
+		t.Error("с hmac_key хеш не изменился")
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	report := verifyChain(sampleEntries())
+	if !report.Valid() || report.Checked != 3 || report.LastID != 3 {
+		t.Fatalf("целая цепочка: %+v", report)
+	}
+
+	// записи до появления цепочки пропускаются
+	legacy := append([]models.AuditLog{{ID: 0, Action: "login"}}, sampleEntries()...)
+	if report := verifyChain(legacy); !report.Valid() || report.Unchained != 1 || report.Checked != 3 {
+		t.Fatalf("со старыми записями: %+v", report)
+	}
+
+	tampered := sampleEntries()
+	tampered[1].IP = "192.168.0.1"
+	report = verifyChain(tampered)
+	if report.Valid() || report.Break.ID != 2 || report.Break.Reason != HashMismatch {
+		t.Fatalf("изменённая запись: %+v", report)
+	}
+
+	removed := sampleEntries()
+	removed = append(removed[:1], removed[2:]...)
+	report = verifyChain(removed)
+	if report.Valid() || report.Break.ID != 3 || report.Break.Reason != PrevHashMismatch {
+		t.Fatalf("удалённая запись: %+v", report)
+	}
+}
+
+func TestVerifyRedactedEntry(t *testing.T) {
+	entries := sampleEntries()
+	if !redactEntry(&entries[1], 1) {
+		t.Fatal("в записи нечего стирать")
+	}
+	entries[1].RedactedHash = Hash(entries[1])
+	// событие дописывается к цепочке как есть: testChain пересчитал бы
+	// hash стёртой записи
+	withEvent := func(entries []models.AuditLog, hashes map[uint]string) []models.AuditLog {
+		last := entries[len(entries)-1]
+		event := redactionEvent(hashes)
+		event.ID, event.Timestamp = last.ID+1, last.Timestamp
+		normalize(&event)
+		event.PrevHash = last.Hash
+		event.Hash = Hash(event)
+		return append(slices.Clone(entries), event)
+	}
+
+	report := verifyChain(withEvent(entries, map[uint]string{2: entries[1].RedactedHash}))
+	if !report.Valid() || report.Redacted != 1 || report.Checked != 4 {
+		t.Fatalf("цепочка со стёртой записью: %+v", report)
+	}
+
+	// без события о стирании redacted_hash не принимается: без hmac_key
+	// его пересчитает любой
+	if report := verifyChain(entries); report.Valid() || report.Break.ID != 2 || report.Break.Reason != RedactionUnrecorded {
+		t.Fatalf("стирание без события: %+v", report)
+	}
+
+	// подделка с пересчитанным redacted_hash расходится с событием
+	forged := withEvent(entries, map[uint]string{2: entries[1].RedactedHash})
+	forged[1].Action = "login"
+	forged[1].RedactedHash = Hash(forged[1])
+	if report := verifyChain(forged); report.Valid() || report.Break.Reason != RedactionUnrecorded {
+		t.Fatalf("подделанная стёртая запись: %+v", report)
+	}
+
+	// без пересчёта подделка ловится по самому redacted_hash
+	forged = withEvent(entries, map[uint]string{2: entries[1].RedactedHash})
+	forged[1].Action = "login"
+	if report := verifyChain(forged); report.Valid() || report.Break.Reason != HashMismatch {
+		t.Fatalf("подделанная стёртая запись: %+v", report)
+	}
+}
+
+func TestRecordedRedactions(t *testing.T) {
+	events := testChain(
+		redactionEvent(map[uint]string{2: "a", 5: "b"}),
+		models.AuditLog{Action: "login", Metadata: Details("entries", map[string]any{"2": "x"}).JSON()},
+		redactionEvent(map[uint]string{2: "c"}),
+	)
+	got := recordedRedactions(events)
+	if len(got) != 2 || got[2] != "c" || got[5] != "b" {
+		t.Errorf("recordedRedactions = %v", got)
+	}
+}
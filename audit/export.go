@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"Blog/models"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Entry — запись журнала в архивах и выгрузках. Хеши входят, чтобы
+// выгруженную часть цепочки можно было проверить без базы.
+type Entry struct {
+	ID        uint            `json:"id"`
+	UserID    uint            `json:"user_id"`
+	Action    string          `json:"action"`
+	Object    string          `json:"object"`
+	ObjectID  uint            `json:"object_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Metadata  json.RawMessage `json:"metadata"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+	// есть, если личные данные в записи стёрты, см. RedactUser
+	RedactedHash string `json:"redacted_hash,omitempty"`
+}
+
+func EntryOf(log models.AuditLog) Entry {
+	return Entry{
+		ID:        log.ID,
+		UserID:    log.UserID,
+		Action:    log.Action,
+		Object:    log.Object,
+		ObjectID:  log.ObjectID,
+		Timestamp: log.Timestamp.UTC(),
+		IP:        log.IP,
+		UserAgent: log.UserAgent,
+		Metadata:  log.Metadata,
+		PrevHash:  log.PrevHash,
+		Hash:      log.Hash,
+
+		RedactedHash: log.RedactedHash,
+	}
+}
+
+// Writer пишет записи журнала в одном из форматов выгрузки.
+type Writer interface {
+	Write(models.AuditLog) error
+	Flush() error
+}
+
+// NewWriter возвращает Writer для формата csv или ndjson; ok=false для
+// неизвестного формата.
+func NewWriter(w io.Writer, format string) (Writer, bool) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w), true
+	case "ndjson":
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, true
+	}
+	return nil, false
+}
+
+// ContentType — MIME-тип формата выгрузки.
+func ContentType(format string) string {
+	if format == "csv" {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(log models.AuditLog) error {
+	return w.enc.Encode(EntryOf(log))
+}
+
+func (w *ndjsonWriter) Flush() error { return nil }
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	cw.w.Write([]string{"id", "user_id", "action", "object", "object_id", "timestamp",
+		"ip", "user_agent", "metadata", "prev_hash", "hash", "redacted_hash"})
+	return cw
+}
+
+func (w *csvWriter) Write(log models.AuditLog) error {
+	e := EntryOf(log)
+	return w.w.Write([]string{
+		strconv.FormatUint(uint64(e.ID), 10),
+		strconv.FormatUint(uint64(e.UserID), 10),
+		e.Action,
+		e.Object,
+		strconv.FormatUint(uint64(e.ObjectID), 10),
+		e.Timestamp.Format(time.RFC3339Nano),
+		e.IP,
+		e.UserAgent,
+		string(e.Metadata),
+		e.PrevHash,
+		e.Hash,
+		e.RedactedHash,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"Blog/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// fileSink дописывает записи в NDJSON-файл и синхронизирует его после
+// каждой пачки. Файл открывается заново на каждую пачку, поэтому
+// logrotate может переименовывать его без сигналов.
+type fileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newFileSink(cfg config.AuditSink) *fileSink {
+	return &fileSink{path: cfg.Path}
+}
+
+func (s *fileSink) Send(_ context.Context, entries []Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileSink) Close() error { return nil }
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Metadata — содержимое колонки metadata (JSONB).
+type Metadata struct {
+	RequestID string `json:"request_id,omitempty"`
+	// откуда запись без HTTP-запроса: "cli" или "job". Ставит только сервер
+	// (utils.SystemAuditEntry); User-Agent для этого не годится — его
+	// присылает клиент
+	Source string `json:"source,omitempty"`
+	// имена изменённых полей, по ним фильтрует API (?changed=email)
+	Changed []string          `json:"changed,omitempty"`
+	Changes map[string]Change `json:"changes,omitempty"`
+	// прочие сведения о действии: имя файла, причина и т.п.
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Change — значение поля до и после действия.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Redacted заменяет значения полей с политикой redact.
+const Redacted = "[redacted]"
+
+// JSON сериализует метаданные, заполняя Changed по Changes.
+func (m Metadata) JSON() json.RawMessage {
+	m.Changed = m.Changed[:0:0]
+	for field := range m.Changes {
+		m.Changed = append(m.Changed, field)
+	}
+	sort.Strings(m.Changed)
+
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// Details — метаданные с одними дополнительными сведениями.
+func Details(kv ...any) Metadata {
+	details := make(map[string]any, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, _ := kv[i].(string)
+		details[key] = kv[i+1]
+	}
+	return Metadata{Details: details}
+}
+
+var naming = schema.NamingStrategy{}
+
+// Diff сравнивает две версии модели и возвращает изменённые поля под
+// именами колонок. Политика поля задаётся тегом audit:
+//
+//	audit:"redact" — факт изменения пишется, значения заменяются на Redacted;
+//	audit:"-"      — поле не попадает в журнал совсем.
+func Diff[T any](before, after T) map[string]Change {
+	changes := map[string]Change{}
+	diffStruct(reflect.ValueOf(before), reflect.ValueOf(after), changes)
+	return changes
+}
+
+// sameValue сравнивает значения так, как они попадут в журнал. Время
+// сравнивается как момент: другая локация или монотонные часы — не
+// изменение.
+func sameValue(b, a any) bool {
+	switch bt := b.(type) {
+	case time.Time:
+		return bt.Equal(a.(time.Time))
+	case *time.Time:
+		at := a.(*time.Time)
+		if bt == nil || at == nil {
+			return bt == at
+		}
+		return bt.Equal(*at)
+	}
+	bj, _ := json.Marshal(b)
+	aj, _ := json.Marshal(a)
+	return bytes.Equal(bj, aj)
+}
+
+func diffStruct(before, after reflect.Value, changes map[string]Change) {
+	t := before.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		policy := f.Tag.Get("audit")
+		if policy == "-" {
+			continue
+		}
+
+		b, a := before.Field(i).Interface(), after.Field(i).Interface()
+		if sameValue(b, a) {
+			continue
+		}
+		if policy == "redact" {
+			b, a = Redacted, Redacted
+		}
+		changes[naming.ColumnName("", f.Name)] = Change{Before: b, After: a}
+	}
+}
+
+// canonical приводит JSON к виду, не зависящему от того, как его сохранил
+// Postgres: JSONB меняет порядок ключей и пробелы.
+func canonical(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type profile struct {
+		Nickname  string
+		Password  string `audit:"redact"`
+		Token     string `audit:"-"`
+		UpdatedAt time.Time
+		Until     *time.Time
+		secret    string
+	}
+
+	ts := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	local := ts.In(time.FixedZone("", 5*3600))
+	// время в другой локации — тот же момент, не изменение
+	before := profile{Nickname: "old", Password: "hash1", Token: "t1", UpdatedAt: ts, Until: &ts, secret: "a"}
+	after := profile{Nickname: "new", Password: "hash2", Token: "t2", UpdatedAt: local, Until: &local, secret: "b"}
+
+	changes := Diff(before, after)
+	want := map[string]Change{
+		"nickname": {Before: "old", After: "new"},
+		"password": {Before: Redacted, After: Redacted},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v", changes)
+	}
+	for field, change := range want {
+		if changes[field] != change {
+			t.Errorf("%s = %v, want %v", field, changes[field], change)
+		}
+	}
+
+	if got := Diff(before, before); len(got) != 0 {
+		t.Errorf("без изменений: %v", got)
+	}
+
+	after = before
+	after.Until = nil
+	if got := Diff(before, after); len(got) != 1 || got["until"].After != (*time.Time)(nil) {
+		t.Errorf("снятый срок: %v", got)
+	}
+}
+
+func TestMetadataJSON(t *testing.T) {
+	m := Metadata{Changes: map[string]Change{"role": {}, "email": {}}}
+	var out struct {
+		Changed []string `json:"changed"`
+	}
+	if err := json.Unmarshal(m.JSON(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Changed) != 2 || out.Changed[0] != "email" || out.Changed[1] != "role" {
+		t.Errorf("changed = %v", out.Changed)
+	}
+
+	if got := Details("reason", "unknown_email").Details["reason"]; got != "unknown_email" {
+		t.Errorf("Details: %v", got)
+	}
+}
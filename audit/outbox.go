@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"Blog/models"
+	"context"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	batchSize     = 500
+	flushInterval = time.Second
+
+	// сколько записей поставлено в outbox с последнего переноса; набрав
+	// batchSize, RunOutbox не ждёт тикера
+	pending atomic.Int64
+	kick    = make(chan struct{}, 1)
+)
+
+func enqueue(ctx context.Context, db *gorm.DB, entry models.AuditLog) error {
+	row := models.AuditOutbox{
+		UserID:    entry.UserID,
+		Action:    entry.Action,
+		Object:    entry.Object,
+		ObjectID:  entry.ObjectID,
+		Timestamp: entry.Timestamp,
+		IP:        entry.IP,
+		UserAgent: entry.UserAgent,
+		Metadata:  entry.Metadata,
+	}
+	if err := db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+
+	// транзакция ещё может откатиться; лишний перенос ничего не сломает
+	if pending.Add(1) >= int64(batchSize) {
+		select {
+		case kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// RunOutbox переносит записи из outbox в цепочку пачками по batchSize, пока
+// не отменён ctx. На выходе переносит всё, что осталось, уже с отдельным
+// таймаутом; что не успеет, останется в outbox до следующего запуска.
+// Outbox разбирается и в синхронном режиме: там могут остаться записи после
+// переключения из асинхронного.
+func RunOutbox(ctx context.Context, db *gorm.DB, shutdownTimeout time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := Flush(flushCtx, db)
+			cancel()
+			if err != nil {
+				slog.Error("Не удалось перенести outbox аудита при остановке", "error", err)
+			}
+			return
+		case <-ticker.C:
+		case <-kick:
+		}
+		if err := Flush(ctx, db); err != nil && ctx.Err() == nil {
+			slog.Error("Ошибка при переносе outbox аудита", "error", err)
+		}
+	}
+}
+
+// Flush переносит записи из outbox, пока он не опустеет.
+func Flush(ctx context.Context, db *gorm.DB) error {
+	pending.Store(0)
+	for {
+		moved, err := flushBatch(ctx, db)
+		if err != nil || moved < batchSize {
+			return err
+		}
+	}
+}
+
+// flushBatch переносит одну пачку в одной транзакции: записи появляются в
+// цепочке и исчезают из outbox одновременно. Несколько экземпляров
+// приложения разбирают outbox по очереди под блокировкой цепочки.
+func flushBatch(ctx context.Context, db *gorm.DB) (int, error) {
+	var moved int
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLock).Error; err != nil {
+			return err
+		}
+
+		var rows []models.AuditOutbox
+		if err := tx.Order("id").Limit(batchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		entries := make([]models.AuditLog, len(rows))
+		ids := make([]uint, len(rows))
+		for i, row := range rows {
+			entries[i] = models.AuditLog{
+				UserID:    row.UserID,
+				Action:    row.Action,
+				Object:    row.Object,
+				ObjectID:  row.ObjectID,
+				Timestamp: row.Timestamp,
+				IP:        row.IP,
+				UserAgent: row.UserAgent,
+				Metadata:  row.Metadata,
+			}
+			normalize(&entries[i])
+			ids[i] = row.ID
+		}
+		if err := appendLocked(tx, entries); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.AuditOutbox{}, ids).Error; err != nil {
+			return err
+		}
+		moved = len(rows)
+		return nil
+	})
+	return moved, err
+}
@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"Blog/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// Стирание личных данных из журнала по запросу на удаление аккаунта. Сама
+// запись остаётся: стираются IP, User-Agent и значения личных полей в
+// metadata. Hash записи не меняется — на него ссылается prev_hash
+// следующей. Хеш нового содержимого с тем же prev_hash пишется в
+// redacted_hash, а id записей и их redacted_hash — в событие
+// redact_entries, которое само встаёт в цепочку. Verify принимает стёртую
+// запись, только если её redacted_hash совпадает с последним событием,
+// где она упомянута: иначе без hmac_key любую запись можно было бы
+// переписать, пересчитав ей redacted_hash.
+//
+// Уже выгруженные архивы и записи, отправленные внешним получателям, этим
+// не меняются: их срок хранения задаётся отдельно.
+
+// RedactAction — действие события, в котором перечислены стёртые записи.
+const RedactAction = "redact_entries"
+
+// piiChanges — поля пользователя, значения которых стираются из changes.
+var piiChanges = map[string]bool{"email": true, "nickname": true, "avatar_url": true, "avatar_key": true}
+
+// piiDetails — ключи details с личными данными.
+var piiDetails = map[string]bool{"email": true, "file_name": true}
+
+// RedactUser стирает личные данные пользователя из записей, где он автор
+// или объект действия, и из старых записей о неудачных входах с его email
+// (теперь email в них не пишется). Возвращает число изменённых записей.
+// Вызывается в транзакции стирания самого пользователя.
+func RedactUser(ctx context.Context, tx *gorm.DB, userID uint, email string) (int, error) {
+	query := tx.WithContext(ctx).Where("user_id = ? OR (object = ? AND object_id = ?)", userID, "user", userID)
+	if email != "" {
+		value, _ := json.Marshal(Metadata{Details: map[string]any{"email": email}})
+		query = query.Or("metadata @> ?", string(value))
+	}
+
+	var entries []models.AuditLog
+	if err := query.Order("id").Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	redacted := 0
+	hashes := map[uint]string{}
+	for _, entry := range entries {
+		if !redactEntry(&entry, userID) {
+			continue
+		}
+		if entry.Hash != "" {
+			entry.RedactedHash = Hash(entry)
+			hashes[entry.ID] = entry.RedactedHash
+		}
+		// в обход хуков AuditLog: это единственное изменение записей журнала,
+		// которое допускает цепочка
+		err := tx.WithContext(ctx).Exec(
+			"UPDATE audit_logs SET ip = ?, user_agent = ?, metadata = ?, redacted_hash = ? WHERE id = ? AND timestamp = ?",
+			entry.IP, entry.UserAgent, string(entry.Metadata), entry.RedactedHash, entry.ID, entry.Timestamp,
+		).Error
+		if err != nil {
+			return redacted, err
+		}
+		redacted++
+	}
+
+	if len(hashes) > 0 {
+		// сразу в цепочку, минуя outbox: до событий из outbox Verify
+		// считал бы стёртые записи подделанными
+		event := redactionEvent(hashes)
+		if err := Append(ctx, tx, &event); err != nil {
+			return redacted, err
+		}
+	}
+	return redacted, nil
+}
+
+// redactionEvent — запись журнала о стирании: id записей и их redacted_hash.
+func redactionEvent(hashes map[uint]string) models.AuditLog {
+	entries := make(map[string]any, len(hashes))
+	for id, h := range hashes {
+		entries[strconv.FormatUint(uint64(id), 10)] = h
+	}
+	return models.AuditLog{
+		Action:   RedactAction,
+		Object:   "audit_log",
+		Metadata: Details("entries", entries).JSON(),
+	}
+}
+
+// recordedRedactions собирает из событий redact_entries, упорядоченных по
+// id, последний записанный redacted_hash каждой стёртой записи.
+func recordedRedactions(events []models.AuditLog) map[uint]string {
+	hashes := map[uint]string{}
+	for _, event := range events {
+		if event.Action != RedactAction {
+			continue
+		}
+		var metadata struct {
+			Details struct {
+				Entries map[string]string `json:"entries"`
+			} `json:"details"`
+		}
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			continue
+		}
+		for key, h := range metadata.Details.Entries {
+			if id, err := strconv.ParseUint(key, 10, 64); err == nil {
+				hashes[uint(id)] = h
+			}
+		}
+	}
+	return hashes
+}
+
+// redactEntry стирает личные данные в entry и сообщает, изменилось ли что-то.
+// IP и User-Agent стираются у действий самого пользователя и у действий без
+// входа (неудачные попытки входа под его аккаунтом); у действий
+// администраторов над ним они принадлежат администратору и остаются.
+func redactEntry(entry *models.AuditLog, userID uint) bool {
+	changed := false
+	if entry.UserID == userID || (entry.UserID == 0 && entry.IP != "") {
+		if entry.IP != "" || entry.UserAgent != "" {
+			entry.IP, entry.UserAgent = "", ""
+			changed = true
+		}
+	}
+	if metadata, ok := redactMetadata(entry.Metadata); ok {
+		entry.Metadata = metadata
+		changed = true
+	}
+	return changed
+}
+
+func redactMetadata(data json.RawMessage) (json.RawMessage, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return data, false
+	}
+
+	switch m := v.(type) {
+	case string:
+		// у записей до перехода на JSONB metadata — произвольный текст
+		if m == "" || m == Redacted {
+			return data, false
+		}
+		out, _ := json.Marshal(Redacted)
+		return out, true
+	case map[string]any:
+		changed := false
+		if changes, ok := m["changes"].(map[string]any); ok {
+			for field, value := range changes {
+				change, _ := value.(map[string]any)
+				if piiChanges[field] && (change["before"] != Redacted || change["after"] != Redacted) {
+					changes[field] = Change{Before: Redacted, After: Redacted}
+					changed = true
+				}
+			}
+		}
+		if details, ok := m["details"].(map[string]any); ok {
+			for key, value := range details {
+				if piiDetails[key] && value != Redacted {
+					details[key] = Redacted
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return data, false
+		}
+		out, err := json.Marshal(m)
+		if err != nil {
+			return data, false
+		}
+		return canonical(out), true
+	}
+	return data, false
+}
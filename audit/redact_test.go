@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"Blog/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    models.AuditLog
+		changed  bool
+		ip       string
+		metadata string
+	}{
+		{
+			name:     "своё действие",
+			entry:    models.AuditLog{UserID: 7, IP: "10.0.0.1", UserAgent: "curl", Metadata: json.RawMessage(`{"request_id":"r1"}`)},
+			changed:  true,
+			metadata: `{"request_id":"r1"}`,
+		},
+		{
+			name:     "неудачный вход без автора",
+			entry:    models.AuditLog{UserID: 0, ObjectID: 7, IP: "10.0.0.1", Metadata: json.RawMessage(`{"details":{"reason":"wrong_password"}}`)},
+			changed:  true,
+			metadata: `{"details":{"reason":"wrong_password"}}`,
+		},
+		{
+			name: "действие администратора",
+			entry: models.AuditLog{UserID: 1, ObjectID: 7, IP: "10.0.0.9",
+				Metadata: json.RawMessage(`{"changes":{"email":{"before":"a@example.com","after":"b@example.com"},"role":{"before":"user","after":"admin"}}}`)},
+			changed:  true,
+			ip:       "10.0.0.9",
+			metadata: `{"changes":{"email":{"after":"[redacted]","before":"[redacted]"},"role":{"after":"admin","before":"user"}}}`,
+		},
+		{
+			name:     "личные поля в details",
+			entry:    models.AuditLog{UserID: 1, IP: "10.0.0.9", Metadata: json.RawMessage(`{"details":{"email":"a@example.com","file_name":"me.png","size":10}}`)},
+			changed:  true,
+			ip:       "10.0.0.9",
+			metadata: `{"details":{"email":"[redacted]","file_name":"[redacted]","size":10}}`,
+		},
+		{
+			name:     "старая запись со строкой",
+			entry:    models.AuditLog{UserID: 1, Metadata: json.RawMessage(`"сменил email на a@example.com"`)},
+			changed:  true,
+			metadata: `"[redacted]"`,
+		},
+		{
+			name:     "уже стёрта",
+			entry:    models.AuditLog{UserID: 7, Metadata: json.RawMessage(`{"details":{"email":"[redacted]"}}`)},
+			metadata: `{"details":{"email":"[redacted]"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry
+			if changed := redactEntry(&entry, 7); changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if entry.IP != tt.ip {
+				t.Errorf("ip = %q, want %q", entry.IP, tt.ip)
+			}
+			if string(entry.Metadata) != tt.metadata {
+				t.Errorf("metadata = %s, want %s", entry.Metadata, tt.metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"Blog/models"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// retentionLock — ключ advisory lock, чтобы архивировал один экземпляр.
+const retentionLock = 0x61756472
+
+const defaultPartition = "audit_logs_default"
+
+var partitionName = regexp.MustCompile(`^audit_logs_(\d{4})_(\d{2})$`)
+
+// EnsurePartitions создаёт секции audit_logs на текущий и два следующих
+// месяца. Без них записи попадают в секцию по умолчанию, и создать секцию
+// на этот месяц потом уже не получится.
+func EnsurePartitions(ctx context.Context, db *gorm.DB) error {
+	now := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		month := time.Date(now.Year(), now.Month()+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+		if err := db.WithContext(ctx).Exec("SELECT audit_logs_create_partition(?)", month.Format(time.DateOnly)).Error; err != nil {
+			return fmt.Errorf("секция %s: %w", month.Format("2006-01"), err)
+		}
+	}
+	return nil
+}
+
+// EnforceRetention выгружает записи старше keep в сжатые NDJSON-файлы в dir
+// и удаляет их из базы: месячные секции целиком, когда весь месяц старше
+// keep, из секции по умолчанию — построчно. Файл пишется и синхронизируется
+// на диск до удаления записей. Если другой экземпляр уже архивирует, ничего
+// не делает.
+func EnforceRetention(ctx context.Context, db *gorm.DB, dir string, keep time.Duration) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-keep).UTC()
+
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", retentionLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", retentionLock)
+
+		// в секции по умолчанию лежат самые старые записи (без времени)
+		name := defaultPartition + "_" + cutoff.Format("20060102T150405")
+		if err := archive(conn, dir, defaultPartition, name, &cutoff); err != nil {
+			return err
+		}
+
+		partitions, err := expiredPartitions(conn, cutoff)
+		if err != nil {
+			return err
+		}
+		for _, table := range partitions {
+			if err := archive(conn, dir, table, table, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// expiredPartitions — месячные секции, целиком старше cutoff, от старых к новым.
+func expiredPartitions(db *gorm.DB, cutoff time.Time) ([]string, error) {
+	var tables []string
+	err := db.Raw(`SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'audit_logs'::regclass`).Scan(&tables).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []string
+	for _, table := range tables {
+		match := partitionName.FindStringSubmatch(table)
+		if match == nil {
+			continue
+		}
+		month, err := time.Parse("2006-01", match[1]+"-"+match[2])
+		if err != nil {
+			continue
+		}
+		if !month.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, table)
+		}
+	}
+	sort.Strings(expired)
+	return expired, nil
+}
+
+// archive выгружает записи из table в dir/name.ndjson.gz и удаляет их. С
+// before — только записи старше before, иначе вся секция удаляется.
+func archive(db *gorm.DB, dir, table, name string, before *time.Time) error {
+	query := db.Table(table).Order("id")
+	if before != nil {
+		query = query.Where("timestamp < ?", *before)
+	}
+
+	file := filepath.Join(dir, name+".ndjson.gz")
+	record, err := writeArchive(query, file)
+	if err != nil {
+		return fmt.Errorf("архив %s: %w", table, err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if record.Entries > 0 {
+			record.Name = name
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+		}
+		// единственное место, где записи журнала удаляются, поэтому в обход
+		// хуков модели; имя секции проверено по шаблону
+		if before == nil {
+			return tx.Exec("DROP TABLE " + table).Error
+		}
+		if record.Entries == 0 {
+			return nil
+		}
+		return tx.Exec("DELETE FROM "+table+" WHERE timestamp < ? AND id <= ?", *before, record.LastID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("удаление %s: %w", table, err)
+	}
+	if record.Entries == 0 {
+		os.Remove(file)
+		return nil
+	}
+	slog.Info("Журнал аудита заархивирован", "table", table, "file", file, "entries", record.Entries)
+	return nil
+}
+
+func writeArchive(query *gorm.DB, file string) (models.AuditArchive, error) {
+	record := models.AuditArchive{File: file}
+
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return record, err
+	}
+	defer os.Remove(tmp) // после переименования ничего не удалит
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	w, _ := NewWriter(gz, "ndjson")
+
+	rows, err := query.Rows()
+	if err != nil {
+		return record, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := query.ScanRows(rows, &entry); err != nil {
+			return record, err
+		}
+		if err := w.Write(entry); err != nil {
+			return record, err
+		}
+		if record.Entries == 0 {
+			record.FirstID = entry.ID
+		}
+		record.Entries++
+		record.LastID = entry.ID
+		record.LastHash = entry.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return record, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return record, err
+	}
+	if err := f.Sync(); err != nil {
+		return record, err
+	}
+	if err := f.Close(); err != nil {
+		return record, err
+	}
+	return record, os.Rename(tmp, file)
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"Blog/config"
+	"Blog/metrics"
+	"Blog/models"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+// Sink — внешний получатель журнала. Send получает пачку по порядку id и
+// должен либо доставить её целиком, либо вернуть ошибку: тогда та же пачка
+// придёт снова.
+type Sink interface {
+	Send(ctx context.Context, entries []Entry) error
+	Close() error
+}
+
+// Записи пересылаются не из LogAudit, а отдельным проходом по уже
+// зафиксированным записям: получатель не увидит действие, транзакция
+// которого откатилась, и ничего не потеряет при падении процесса.
+
+// sinkLock — ключ advisory lock, чтобы пересылал один экземпляр.
+const sinkLock = 0x61756473
+
+const sinkBatchSize = 200
+
+type forwarder struct {
+	name    string
+	sink    Sink
+	actions map[string]bool // nil — все действия
+}
+
+var forwarders []forwarder
+
+// ConfigureSinks создаёт получателей из конфигурации. Соединения
+// открываются при первой отправке.
+func ConfigureSinks(cfgs []config.AuditSink) error {
+	CloseSinks()
+	for _, cfg := range cfgs {
+		sink, err := NewSink(cfg)
+		if err != nil {
+			return fmt.Errorf("audit sink %s: %w", cfg.Name, err)
+		}
+		f := forwarder{name: cfg.Name, sink: sink}
+		if len(cfg.Actions) > 0 {
+			f.actions = make(map[string]bool, len(cfg.Actions))
+			for _, action := range cfg.Actions {
+				f.actions[action] = true
+			}
+		}
+		forwarders = append(forwarders, f)
+	}
+	return nil
+}
+
+// NewSink создаёт получателя по типу из конфигурации.
+func NewSink(cfg config.AuditSink) (Sink, error) {
+	switch cfg.Type {
+	case "syslog":
+		return newSyslogSink(cfg)
+	case "webhook":
+		return newWebhookSink(cfg), nil
+	case "file":
+		return newFileSink(cfg), nil
+	}
+	return nil, fmt.Errorf("неизвестный тип %q", cfg.Type)
+}
+
+func CloseSinks() {
+	for _, f := range forwarders {
+		if err := f.sink.Close(); err != nil {
+			slog.Error("Ошибка при закрытии получателя журнала аудита", "sink", f.name, "error", err)
+		}
+	}
+	forwarders = nil
+}
+
+// RunSinks пересылает новые записи журнала получателям, пока не отменён ctx.
+func RunSinks(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	if len(forwarders) == 0 {
+		return
+	}
+	defer CloseSinks()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Forward(ctx, db); err != nil && ctx.Err() == nil {
+			slog.Error("Ошибка при пересылке журнала аудита", "error", err)
+		}
+	}
+}
+
+// Forward отправляет каждому получателю всё, что появилось с прошлого раза.
+// Ошибка одного получателя не задерживает остальных.
+func Forward(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", sinkLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", sinkLock)
+
+		for _, f := range forwarders {
+			if err := f.forward(ctx, conn); err != nil {
+				metrics.AuditForwardFailures.WithLabelValues(f.name).Inc()
+				slog.Error("Не удалось переслать журнал аудита", "sink", f.name, "error", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (f forwarder) forward(ctx context.Context, db *gorm.DB) error {
+	lastID, err := sinkCursor(db, f.name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var logs []models.AuditLog
+		if err := db.Where("id > ?", lastID).Order("id").Limit(sinkBatchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if entries := f.filter(logs); len(entries) > 0 {
+			if err := f.sink.Send(ctx, entries); err != nil {
+				return err
+			}
+			metrics.AuditForwarded.WithLabelValues(f.name).Add(float64(len(entries)))
+		}
+
+		lastID = logs[len(logs)-1].ID
+		if err := saveSinkCursor(db, f.name, lastID); err != nil {
+			return err
+		}
+		if len(logs) < sinkBatchSize {
+			return nil
+		}
+	}
+}
+
+// filter оставляет записи с действиями, которые принимает получатель.
+func (f forwarder) filter(logs []models.AuditLog) []Entry {
+	entries := make([]Entry, 0, len(logs))
+	for _, log := range logs {
+		if f.actions == nil || f.actions[log.Action] {
+			entries = append(entries, EntryOf(log))
+		}
+	}
+	return entries
+}
+
+// sinkCursor возвращает id последней пересланной записи. Новый получатель
+// начинает с текущего конца журнала: историю в SIEM можно загрузить из
+// выгрузки.
+func sinkCursor(db *gorm.DB, sink string) (uint, error) {
+	var ids []uint
+	if err := db.Table("audit_sink_cursors").Where("sink = ?", sink).Pluck("last_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	var last uint
+	if err := db.Model(&models.AuditLog{}).Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+	return last, saveSinkCursor(db, sink, last)
+}
+
+func saveSinkCursor(db *gorm.DB, sink string, lastID uint) error {
+	return db.Table("audit_sink_cursors").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sink"}},
+		DoUpdates: clause.Assignments(map[string]any{"last_id": lastID, "updated_at": time.Now()}),
+	}).Create(map[string]any{"sink": sink, "last_id": lastID, "updated_at": time.Now()}).Error
+}
@@ -0,0 +1,310 @@
+package audit
+
+import (
+	"Blog/config"
+	"Blog/models"
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sinkEntry(id uint, action string) Entry {
+	return Entry{
+		ID:        id,
+		UserID:    1,
+		Action:    action,
+		Object:    "post",
+		ObjectID:  id,
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		IP:        "10.0.0.1",
+		Metadata:  json.RawMessage(`{"details":{"title":"a]\"b"}}`),
+		Hash:      "abc",
+	}
+}
+
+// syslogHeader — заголовок RFC 5424 до SD-ELEMENT: PRI 13*8+5, версия 1,
+// время с микросекундами в UTC, HOSTNAME, APP-NAME, PROCID и MSGID.
+var syslogHeader = regexp.MustCompile(`^<109>1 2026-01-02T03:04:05\.123456Z \S+ blog \d+ create_post \[audit@32473 `)
+
+func checkSyslogMessage(t *testing.T, msg string, id uint) {
+	t.Helper()
+	if !syslogHeader.MatchString(msg) {
+		t.Fatalf("заголовок не по RFC 5424: %q", msg)
+	}
+	if want := ` id="` + strconv.Itoa(int(id)) + `" `; !strings.Contains(msg, want) {
+		t.Errorf("нет %s в %q", want, msg)
+	}
+	sd, body, ok := strings.Cut(msg, "] \ufeff")
+	if !ok {
+		t.Fatalf("нет BOM перед текстом: %q", msg)
+	}
+	if strings.Contains(sd, `title`) {
+		t.Errorf("metadata не должна попадать в SD-ELEMENT: %q", sd)
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(body), &entry); err != nil || entry.ID != id {
+		t.Errorf("текст сообщения %q: %v", body, err)
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := newSyslogSink(config.AuditSink{Network: "udp", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Send(context.Background(), []Entry{sinkEntry(1, "create_post"), sinkEntry(2, "create_post")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// по UDP — одно сообщение на датаграмму, без длины впереди
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, id := range []uint{1, 2} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSyslogMessage(t, string(buf[:n]), id)
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// RFC 6587: MSG-LEN SP SYSLOG-MSG
+		r := bufio.NewReader(conn)
+		for {
+			prefix, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+			if err != nil {
+				received <- "плохая длина " + prefix
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	sink, err := newSyslogSink(config.AuditSink{Network: "tcp", Address: ln.Addr().String(), Facility: 13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Send(context.Background(), []Entry{sinkEntry(1, "create_post"), sinkEntry(2, "create_post")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []uint{1, 2} {
+		select {
+		case msg := <-received:
+			checkSyslogMessage(t, msg, id)
+		case <-time.After(5 * time.Second):
+			t.Fatal("сообщение не дошло")
+		}
+	}
+}
+
+func TestSyslogEscaping(t *testing.T) {
+	s := &syslogSink{facility: 13, hostname: "my host"}
+	entry := sinkEntry(1, "create post")
+	entry.IP = `a"b\c]d`
+	msg := string(s.format(entry))
+	if !strings.Contains(msg, ` my_host blog `) || !strings.Contains(msg, ` create_post [`) {
+		t.Errorf("пробелы в полях заголовка не заменены: %q", msg)
+	}
+	if !strings.Contains(msg, `ip="a\"b\\c\]d"`) {
+		t.Errorf("значение SD-PARAM не экранировано: %q", msg)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	const secret = "0123456789abcdef"
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		attempt := len(attempts)
+		mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("X-Audit-Timestamp") + "." + string(body)))
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Audit-Signature") != want {
+			t.Errorf("подпись %q, want %q", r.Header.Get("X-Audit-Signature"), want)
+		}
+		var payload struct {
+			Entries []Entry `json:"entries"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil || len(payload.Entries) != 2 {
+			t.Errorf("тело %s: %v", body, err)
+		}
+
+		// две первые попытки — ошибка сервера, третья проходит
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := newWebhookSink(config.AuditSink{URL: srv.URL, Secret: secret})
+	sink.backoff = 20 * time.Millisecond
+	if err := sink.Send(context.Background(), []Entry{sinkEntry(1, "create_post"), sinkEntry(2, "delete_post")}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 {
+		t.Fatalf("попыток %d, want 3", len(attempts))
+	}
+	// пауза удваивается: 20ms, затем 40ms
+	if d := attempts[1].Sub(attempts[0]); d < 20*time.Millisecond {
+		t.Errorf("первая пауза %s", d)
+	}
+	if d := attempts[2].Sub(attempts[1]); d < 40*time.Millisecond {
+		t.Errorf("вторая пауза %s", d)
+	}
+}
+
+func TestWebhookSinkGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{"5xx повторяется max_retries раз", http.StatusInternalServerError, 3},
+		{"429 повторяется", http.StatusTooManyRequests, 3},
+		{"4xx не повторяется", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				attempts++
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			sink := newWebhookSink(config.AuditSink{URL: srv.URL, Secret: "0123456789abcdef", MaxRetries: 2})
+			sink.backoff = time.Millisecond
+			if err := sink.Send(context.Background(), []Entry{sinkEntry(1, "create_post")}); err == nil {
+				t.Fatal("ошибка сервера должна вернуться из Send")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != tt.attempts {
+				t.Errorf("попыток %d, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	sink := newFileSink(config.AuditSink{Path: path})
+
+	if err := sink.Send(context.Background(), []Entry{sinkEntry(1, "create_post")}); err != nil {
+		t.Fatal(err)
+	}
+	// файл переименовали, как это делает logrotate: следующая пачка идёт в новый
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), []Entry{sinkEntry(2, "create_post"), sinkEntry(3, "delete_post")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, ids := range map[string][]uint{path + ".1": {1}, path: {2, 3}} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != len(ids) {
+			t.Fatalf("%s: %d строк, want %d", file, len(lines), len(ids))
+		}
+		for i, line := range lines {
+			var entry Entry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.ID != ids[i] {
+				t.Errorf("%s: строка %q: %v", file, line, err)
+			}
+		}
+	}
+}
+
+func TestSinkActionsFilter(t *testing.T) {
+	dir := t.TempDir()
+	err := ConfigureSinks([]config.AuditSink{
+		{Name: "all", Type: "file", Path: filepath.Join(dir, "all.ndjson")},
+		{Name: "posts", Type: "file", Path: filepath.Join(dir, "posts.ndjson"), Actions: []string{"create_post", "delete_post"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer CloseSinks()
+
+	logs := []models.AuditLog{
+		{ID: 1, Action: "login"},
+		{ID: 2, Action: "create_post"},
+		{ID: 3, Action: "update_user"},
+		{ID: 4, Action: "delete_post"},
+	}
+	want := map[string][]uint{"all": {1, 2, 3, 4}, "posts": {2, 4}}
+	for _, f := range forwarders {
+		var ids []uint
+		for _, entry := range f.filter(logs) {
+			ids = append(ids, entry.ID)
+		}
+		if !slices.Equal(ids, want[f.name]) {
+			t.Errorf("%s: получили %v, want %v", f.name, ids, want[f.name])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"Blog/config"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookSink отправляет пачку записей POST-запросом:
+//
+//	{"entries": [...]}
+//
+// Подпись — X-Audit-Signature: sha256=<hex HMAC-SHA256(secret, timestamp + "." + тело)>,
+// где timestamp — X-Audit-Timestamp в Unix-секундах; получатель проверяет
+// подпись и отбрасывает старые timestamp. Доставка хотя бы один раз:
+// повторы различаются по id записей.
+type webhookSink struct {
+	url        string
+	secret     []byte
+	maxRetries int
+	backoff    time.Duration // пауза перед первым повтором, дальше удваивается
+	client     *http.Client
+}
+
+func newWebhookSink(cfg config.AuditSink) *webhookSink {
+	s := &webhookSink{
+		url:        cfg.URL,
+		secret:     []byte(cfg.Secret),
+		maxRetries: cfg.MaxRetries,
+		backoff:    time.Second,
+		client:     &http.Client{Timeout: cfg.Timeout.Duration},
+	}
+	if s.maxRetries == 0 {
+		s.maxRetries = 3
+	}
+	if s.client.Timeout == 0 {
+		s.client.Timeout = 10 * time.Second
+	}
+	return s
+}
+
+func (s *webhookSink) Send(ctx context.Context, entries []Entry) error {
+	body, err := json.Marshal(map[string][]Entry{"entries": entries})
+	if err != nil {
+		return err
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.maxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post возвращает retry=true для ошибок, которые есть смысл повторить:
+// сеть, 429 и 5xx.
+func (s *webhookSink) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Audit-Timestamp", timestamp)
+	req.Header.Set("X-Audit-Signature", "sha256="+s.sign(timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook ответил %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook ответил %s", resp.Status)
+	}
+}
+
+func (s *webhookSink) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"Blog/audit"
+	"Blog/config"
+	"Blog/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const auditUsage = `использование:
+  blog audit verify        проверить цепочку хешей журнала аудита
+  blog audit test-sinks    отправить тестовую запись всем получателям из audit.sinks
+
+при разрыве цепочки или ошибке отправки команда завершается с ошибкой`
+
+func auditCommand(cfg config.Audit, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана подкоманда\n%s", auditUsage)
+	}
+
+	switch args[0] {
+	case "verify":
+		report, err := audit.Verify(context.Background(), storage.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "проверено записей: %d, до начала цепочки: %d\n", report.Checked, report.Unchained)
+		if report.LastID != 0 {
+			fmt.Fprintf(os.Stdout, "последняя запись: id=%d hash=%s\n", report.LastID, report.LastHash)
+		}
+		if b := report.Break; b != nil {
+			return fmt.Errorf("цепочка разорвана на записи id=%d (%s): ожидалось %q, в базе %q",
+				b.ID, b.Reason, b.Expected, b.Actual)
+		}
+		fmt.Fprintln(os.Stdout, "цепочка цела")
+		return nil
+	case "test-sinks":
+		return testSinks(cfg.Sinks)
+	default:
+		return fmt.Errorf("неизвестная подкоманда %q\n%s", args[0], auditUsage)
+	}
+}
+
+// testSinks отправляет каждому получателю одну запись sink_test без учёта
+// фильтров actions. Запись не попадает в журнал.
+func testSinks(sinks []config.AuditSink) error {
+	if len(sinks) == 0 {
+		return errors.New("в audit.sinks нет получателей")
+	}
+
+	entry := audit.Entry{
+		Action:    "sink_test",
+		Object:    "audit_sink",
+		Timestamp: time.Now().UTC(),
+		UserAgent: "cli",
+		Metadata:  json.RawMessage("{}"),
+	}
+	failed := 0
+	for _, cfg := range sinks {
+		err := sendTestEntry(cfg, entry)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stdout, "%s (%s): ошибка: %v\n", cfg.Name, cfg.Type, err)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s (%s): доставлено\n", cfg.Name, cfg.Type)
+	}
+	if failed > 0 {
+		return fmt.Errorf("не доставлено получателям: %d", failed)
+	}
+	return nil
+}
+
+func sendTestEntry(cfg config.AuditSink, entry audit.Entry) error {
+	sink, err := audit.NewSink(cfg)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return sink.Send(ctx, []audit.Entry{entry})
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"Blog/audit"
+	"Blog/config"
+	"Blog/logging"
+	"Blog/migrate"
+	"Blog/storage"
+	"context"
+	"fmt"
+	"os"
+)
+
+const usage = `использование: blog [-config файл] [флаги конфигурации] <команда>
+
+команды:
+  serve                          запустить HTTP-сервер (по умолчанию)
+  migrate ...                    управление миграциями, см. blog migrate -h
+  user create ...                создать пользователя (--admin для администратора)
+  user reset-password ...        сменить пароль пользователя
+  user promote ...               изменить роль пользователя
+  seed --fake-users N            создать N тестовых пользователей
+  openapi dump|check             вывести спецификацию или сверить её с маршрутами
+  audit verify|test-sinks        проверить цепочку журнала аудита или отправку в audit.sinks`
+
+// Run выполняет команду args, уже без глобальных флагов конфигурации.
+func Run(cfg *config.Config, args []string) error {
+	logging.Setup(cfg.Log.Level, cfg.Log.Format)
+	audit.Configure(cfg.Audit)
+
+	if len(args) == 0 {
+		return serve(cfg)
+	}
+
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "serve":
+		return serve(cfg)
+	case "migrate":
+		if err := storage.ConnectDB(context.Background(), cfg.Database); err != nil {
+			return err
+		}
+		return migrate.RunCLI(rest)
+	case "user":
+		if err := storage.ConnectDB(context.Background(), cfg.Database); err != nil {
+			return err
+		}
+		return userCommand(rest)
+	case "seed":
+		if err := storage.ConnectDB(context.Background(), cfg.Database); err != nil {
+			return err
+		}
+		return seed(rest)
+	case "audit":
+		if len(rest) > 0 && rest[0] == "test-sinks" {
+			return auditCommand(cfg.Audit, rest)
+		}
+		if err := storage.ConnectDB(context.Background(), cfg.Database); err != nil {
+			return err
+		}
+		return auditCommand(cfg.Audit, rest)
+	case "openapi":
+		return openapiCommand(cfg, rest)
+	case "help", "-h", "--help":
+		fmt.Fprintln(os.Stdout, usage)
+		return nil
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("неизвестная команда %q", cmd)
+	}
+}
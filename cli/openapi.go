@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"Blog/config"
+	"Blog/filestore"
+	"Blog/openapi"
+	"Blog/ratelimit"
+	"Blog/routes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"os"
+)
+
+const openapiUsage = `использование:
+  blog openapi dump     вывести спецификацию OpenAPI в stdout
+  blog openapi check    проверить, что все маршруты описаны в спецификации (для CI)`
+
+func openapiCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана подкоманда\n%s", openapiUsage)
+	}
+
+	switch args[0] {
+	case "dump":
+		_, err := os.Stdout.Write(openapi.JSON())
+		return err
+	case "check":
+		// маршруты файлов зависят от драйвера хранилища; проверяем
+		// с локальным, у которого их больше
+		storageCfg := cfg.Storage
+		storageCfg.Driver = "local"
+		filestore.Init(storageCfg)
+
+		gin.SetMode(gin.ReleaseMode)
+		r, err := routes.NewRouter(cfg, ratelimit.NewMemory())
+		if err != nil {
+			return err
+		}
+		if err := openapi.CheckError(r.Routes()); err != nil {
+			return err
+		}
+		fmt.Println("все маршруты описаны")
+		return nil
+	default:
+		return fmt.Errorf("неизвестная подкоманда %q\n%s", args[0], openapiUsage)
+	}
+}
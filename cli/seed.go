@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func seed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	count := fs.Int("fake-users", 0, "сколько тестовых пользователей создать")
+	password := fs.String("password", "password", "пароль для всех тестовых пользователей")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		return errors.New("укажите --fake-users N")
+	}
+
+	// bcrypt медленный, поэтому хешируем один раз на всех
+	hashed, err := utils.HashPassword(context.Background(), *password)
+	if err != nil {
+		return err
+	}
+
+	batch := utils.RandomToken(3)
+	users := make([]models.User, 0, *count)
+	for i := 1; i <= *count; i++ {
+		nickname := fmt.Sprintf("fake_%s_%d", batch, i)
+		users = append(users, models.User{
+			Nickname: nickname,
+			Email:    nickname + "@example.com",
+			Password: hashed,
+			Role:     "user",
+		})
+	}
+
+	if err := storage.DB.CreateInBatches(users, 500).Error; err != nil {
+		return fmt.Errorf("не удалось создать пользователей: %w", err)
+	}
+
+	fmt.Printf("Создано %d пользователей fake_%s_*@example.com с паролем %q\n", *count, batch, *password)
+	return nil
+}
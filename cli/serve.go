@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"Blog/audit"
+	"Blog/config"
+	"Blog/filestore"
+	"Blog/handlers"
+	"Blog/jobs"
+	"Blog/mail"
+	"Blog/metrics"
+	"Blog/migrate"
+	"Blog/ratelimit"
+	"Blog/routes"
+	"Blog/storage"
+	"Blog/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func serve(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("Не удалось выгрузить трейсы", "error", err)
+		}
+	}()
+
+	if err := storage.ConnectDB(ctx, cfg.Database); err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if err := migrate.New(storage.DB).Up(); err != nil {
+		return fmt.Errorf("ошибка миграции: %w", err)
+	}
+	slog.Info("Миграция завершена")
+
+	filestore.Init(cfg.Storage)
+	mail.Init(cfg.Mail)
+	if err := audit.ConfigureSinks(cfg.Audit.Sinks); err != nil {
+		return err
+	}
+
+	if sqlDB, err := storage.DB.DB(); err == nil {
+		metrics.RegisterDB(sqlDB)
+	}
+
+	var rateBuckets *ratelimit.Postgres
+	var rateBackend ratelimit.Backend = ratelimit.NewMemory()
+	if cfg.RateLimit.Backend == "postgres" {
+		rateBuckets = ratelimit.NewPostgres(storage.DB)
+		rateBackend = rateBuckets
+	}
+
+	r, err := routes.NewRouter(cfg, rateBackend)
+	if err != nil {
+		return err
+	}
+
+	// фоновые задачи останавливаются через отдельный контекст уже после того,
+	// как сервер дообработал запросы
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(fn func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fn(workersCtx)
+		}()
+	}
+	runWorker(func(ctx context.Context) { jobs.CleanupExpiredUploads(ctx, time.Hour) })
+	runWorker(func(ctx context.Context) { jobs.AuditRetention(ctx, cfg.Audit, time.Hour) })
+	runWorker(func(ctx context.Context) { jobs.LiftExpiredBlocks(ctx, time.Minute) })
+	runWorker(func(ctx context.Context) {
+		jobs.BuildDataExports(ctx, cfg.Users.ExportTTL.Duration, cfg.JWT.RefreshTTL.Duration, time.Minute)
+	})
+	if grace := cfg.Users.ErasureGrace.Duration; grace > 0 {
+		runWorker(func(ctx context.Context) { jobs.EraseDeletedUsers(ctx, grace, time.Hour) })
+	}
+	runWorker(func(ctx context.Context) { audit.RunSinks(ctx, storage.DB, cfg.Audit.FlushInterval.Duration) })
+	// при остановке переносит в журнал outbox аудита, накопленный уже
+	// дообработанными запросами
+	runWorker(func(ctx context.Context) { audit.RunOutbox(ctx, storage.DB, cfg.Server.ShutdownTimeout.Duration) })
+	if rateBuckets != nil {
+		runWorker(func(ctx context.Context) { jobs.CleanupRateLimitBuckets(ctx, rateBuckets, 10*time.Minute) })
+	}
+
+	srv := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "addr", cfg.Server.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		slog.Info("Получен сигнал завершения, останавливаем сервер")
+		handlers.MarkDraining()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+		defer cancel()
+		err = srv.Shutdown(shutdownCtx)
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
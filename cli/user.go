@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"Blog/audit"
+	"Blog/dto"
+	"Blog/i18n"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+)
+
+const userUsage = `использование:
+  blog user create --nickname N --email E [--password P] [--admin]
+  blog user reset-password --email E [--password P]
+  blog user promote --email E [--role admin|user]
+
+если пароль не указан, он будет сгенерирован и выведен в консоль`
+
+var validate = i18n.Validator()
+
+var roles = map[string]bool{"user": true, "admin": true}
+
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return errors.New("не указана команда")
+	}
+
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "create":
+		return createUser(rest)
+	case "reset-password":
+		return resetPassword(rest)
+	case "promote":
+		return promoteUser(rest)
+	default:
+		fmt.Fprintln(os.Stderr, userUsage)
+		return fmt.Errorf("неизвестная команда %q", cmd)
+	}
+}
+
+func createUser(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	nickname := fs.String("nickname", "", "никнейм")
+	email := fs.String("email", "", "email")
+	password := fs.String("password", "", "пароль (по умолчанию генерируется)")
+	admin := fs.Bool("admin", false, "выдать права администратора")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = utils.RandomToken(9)
+	}
+
+	input := dto.CreateUserInput{Nickname: *nickname, Email: *email, Password: *password}
+	if err := validate.Struct(input); err != nil {
+		return fmt.Errorf("некорректные данные: %v", i18n.ValidationErrors(i18n.Default, err))
+	}
+
+	hashed, err := utils.HashPassword(context.Background(), input.Password)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{
+		Nickname: input.Nickname,
+		Email:    input.Email,
+		Password: hashed,
+		Role:     "user",
+	}
+	if *admin {
+		user.Role = "admin"
+	}
+	err = storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return auditCLI(tx, "create_user", user.ID, audit.Details("role", user.Role))
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось создать пользователя: %w", err)
+	}
+
+	fmt.Printf("Создан пользователь #%d %s (%s), роль %s\n", user.ID, user.Nickname, user.Email, user.Role)
+	if generated {
+		fmt.Println("Пароль:", input.Password)
+	}
+	return nil
+}
+
+func resetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "email пользователя")
+	password := fs.String("password", "", "новый пароль (по умолчанию генерируется)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := findUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = utils.RandomToken(9)
+	}
+	if len(*password) < 5 {
+		return errors.New("пароль должен быть не короче 5 символов")
+	}
+
+	hashed, err := utils.HashPassword(context.Background(), *password)
+	if err != nil {
+		return err
+	}
+	err = storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", hashed).Error; err != nil {
+			return err
+		}
+		return auditCLI(tx, "reset_password", user.ID, audit.Metadata{
+			Changes: map[string]audit.Change{"password": {Before: audit.Redacted, After: audit.Redacted}},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось сменить пароль: %w", err)
+	}
+
+	fmt.Printf("Пароль пользователя #%d %s изменён\n", user.ID, user.Email)
+	if generated {
+		fmt.Println("Новый пароль:", *password)
+	}
+	return nil
+}
+
+func promoteUser(args []string) error {
+	fs := flag.NewFlagSet("user promote", flag.ContinueOnError)
+	email := fs.String("email", "", "email пользователя")
+	role := fs.String("role", "admin", "новая роль")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !roles[*role] {
+		return fmt.Errorf("неизвестная роль %q", *role)
+	}
+
+	user, err := findUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+	if user.Role == *role {
+		fmt.Printf("У пользователя #%d уже роль %s\n", user.ID, user.Role)
+		return nil
+	}
+
+	oldRole := user.Role
+	err = storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("role", *role).Error; err != nil {
+			return err
+		}
+		return auditCLI(tx, "change_role", user.ID, audit.Metadata{
+			Changes: map[string]audit.Change{"role": {Before: oldRole, After: *role}},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось изменить роль: %w", err)
+	}
+
+	fmt.Printf("Роль пользователя #%d %s: %s -> %s\n", user.ID, user.Email, oldRole, *role)
+	return nil
+}
+
+func findUserByEmail(email string) (models.User, error) {
+	var user models.User
+	if email == "" {
+		return user, errors.New("не указан --email")
+	}
+	if err := storage.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return user, fmt.Errorf("пользователь %s не найден", email)
+	}
+	return user, nil
+}
+
+// auditCLI пишет запись от имени консоли. В асинхронном режиме запись
+// остаётся в outbox, пока её не перенесёт запущенный сервер.
+func auditCLI(tx *gorm.DB, action string, userID uint, metadata audit.Metadata) error {
+	return utils.WriteAudit(context.Background(), tx, utils.SystemAuditEntry("cli", action, "user", userID, metadata))
+}
@@ -0,0 +1,513 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config собирается в порядке: значения по умолчанию, файл, переменные
+// окружения, флаги командной строки. Каждый следующий источник перекрывает
+// предыдущий.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Database  Database  `yaml:"database" toml:"database"`
+	JWT       JWT       `yaml:"jwt" toml:"jwt"`
+	Storage   Storage   `yaml:"storage" toml:"storage"`
+	Limits    Limits    `yaml:"limits" toml:"limits"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Mail      Mail      `yaml:"mail" toml:"mail"`
+	Users     Users     `yaml:"users" toml:"users"`
+	Audit     Audit     `yaml:"audit" toml:"audit"`
+}
+
+type Server struct {
+	Addr            string   `yaml:"addr" toml:"addr" env:"BLOG_SERVER_ADDR"`
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout" env:"BLOG_SERVER_READ_TIMEOUT"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout" env:"BLOG_SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"BLOG_SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"BLOG_SERVER_SHUTDOWN_TIMEOUT"`
+	// адреса и подсети прокси, чьим X-Forwarded-For можно верить; пусто —
+	// IP клиента берётся из соединения
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type Database struct {
+	DSN string `yaml:"dsn" toml:"dsn" env:"BLOG_DATABASE_DSN"`
+	// сколько ждать появления базы при старте, прежде чем сдаться
+	ConnectTimeout Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"BLOG_DATABASE_CONNECT_TIMEOUT"`
+	// запросы дольше этого порога пишутся в лог как warn (0 — отключить)
+	SlowQueryThreshold Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold" env:"BLOG_DATABASE_SLOW_QUERY_THRESHOLD"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"BLOG_TRACING_EXPORTER"` // none, stdout или otlp
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" env:"BLOG_TRACING_ENDPOINT"` // host:port OTLP/HTTP коллектора
+	Insecure    bool    `yaml:"insecure" toml:"insecure" env:"BLOG_TRACING_INSECURE"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"BLOG_TRACING_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"BLOG_TRACING_SAMPLE_RATIO"`
+}
+
+type RateLimit struct {
+	Backend  string          `yaml:"backend" toml:"backend"` // memory или postgres
+	Login    RateLimitPolicy `yaml:"login" toml:"login"`
+	Register RateLimitPolicy `yaml:"register" toml:"register"`
+	Refresh  RateLimitPolicy `yaml:"refresh" toml:"refresh"`
+	Upload   RateLimitPolicy `yaml:"upload" toml:"upload"`
+	API      RateLimitPolicy `yaml:"api" toml:"api"` // все остальные маршруты с авторизацией
+}
+
+// RateLimitPolicy — token bucket: Burst запросов подряд, затем PerMinute в минуту.
+// PerMinute = 0 отключает лимит.
+type RateLimitPolicy struct {
+	PerMinute float64 `yaml:"per_minute" toml:"per_minute"`
+	Burst     int     `yaml:"burst" toml:"burst"`
+	Key       string  `yaml:"key" toml:"key"` // ip, user или token
+}
+
+type Mail struct {
+	Driver   string `yaml:"driver" toml:"driver"` // log (только пишет в лог) или smtp
+	From     string `yaml:"from" toml:"from"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	// адрес сайта для ссылок в письмах
+	SiteURL string `yaml:"site_url" toml:"site_url"`
+}
+
+type Users struct {
+	// через сколько после удаления аккаунта его личные данные стираются
+	// безвозвратно; до этого пользователя можно восстановить. 0 — не стирать
+	ErasureGrace Duration `yaml:"erasure_grace" toml:"erasure_grace"`
+	// сколько хранится архив выгрузки личных данных (POST /me/export)
+	ExportTTL Duration `yaml:"export_ttl" toml:"export_ttl"`
+}
+
+type Audit struct {
+	// ключ HMAC для цепочки хешей журнала; пусто — обычный SHA-256.
+	// Смена ключа ломает проверку уже записанной цепочки.
+	HMACKey string `yaml:"hmac_key" toml:"hmac_key"`
+
+	// sync — запись сразу встаёт в цепочку внутри транзакции изменения;
+	// async — в транзакции пишется только outbox, в цепочку записи
+	// переносятся пачками в фоне
+	Mode          string   `yaml:"mode" toml:"mode"`
+	BatchSize     int      `yaml:"batch_size" toml:"batch_size"`
+	FlushInterval Duration `yaml:"flush_interval" toml:"flush_interval"`
+
+	// записи старше retention выгружаются в archive_dir и удаляются из
+	// базы помесячно; 0 — хранить всё
+	Retention  Duration `yaml:"retention" toml:"retention"`
+	ArchiveDir string   `yaml:"archive_dir" toml:"archive_dir"`
+
+	// внешние получатели журнала (SIEM); задаются только в файле
+	Sinks []AuditSink `yaml:"sinks" toml:"sinks"`
+}
+
+// AuditSink — куда пересылать записи журнала. Поля зависят от типа:
+// syslog — network и address, webhook — url и secret, file — path.
+type AuditSink struct {
+	Name    string   `yaml:"name" toml:"name"`
+	Type    string   `yaml:"type" toml:"type"`       // syslog, webhook или file
+	Actions []string `yaml:"actions" toml:"actions"` // пусто — все действия
+
+	Network  string `yaml:"network" toml:"network"`   // udp, tcp или tls
+	Address  string `yaml:"address" toml:"address"`   // host:port
+	Facility int    `yaml:"facility" toml:"facility"` // 0 — 13 (log audit)
+	CAFile   string `yaml:"ca_file" toml:"ca_file"`   // для tls, если сертификат не из системных
+
+	URL        string   `yaml:"url" toml:"url"`
+	Secret     string   `yaml:"secret" toml:"secret"`           // ключ HMAC подписи тела
+	Timeout    Duration `yaml:"timeout" toml:"timeout"`         // 0 — 10s
+	MaxRetries int      `yaml:"max_retries" toml:"max_retries"` // 0 — 3
+
+	Path string `yaml:"path" toml:"path"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"BLOG_LOG_LEVEL"`    // debug, info, warn, error
+	Format string `yaml:"format" toml:"format" env:"BLOG_LOG_FORMAT"` // json или text
+}
+
+type JWT struct {
+	Secret     string   `yaml:"secret" toml:"secret" env:"BLOG_JWT_SECRET"`
+	AccessTTL  Duration `yaml:"access_ttl" toml:"access_ttl" env:"BLOG_JWT_ACCESS_TTL"`
+	RefreshTTL Duration `yaml:"refresh_ttl" toml:"refresh_ttl" env:"BLOG_JWT_REFRESH_TTL"`
+}
+
+type Storage struct {
+	Driver     string `yaml:"driver" toml:"driver" env:"BLOG_STORAGE_DRIVER"`
+	LocalDir   string `yaml:"local_dir" toml:"local_dir" env:"BLOG_STORAGE_LOCAL_DIR"`
+	SigningKey string `yaml:"signing_key" toml:"signing_key" env:"BLOG_STORAGE_SIGNING_KEY"`
+	S3         S3     `yaml:"s3" toml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" env:"BLOG_S3_ENDPOINT"`
+	Region    string `yaml:"region" toml:"region" env:"BLOG_S3_REGION"`
+	Bucket    string `yaml:"bucket" toml:"bucket" env:"BLOG_S3_BUCKET"`
+	AccessKey string `yaml:"access_key" toml:"access_key" env:"BLOG_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"BLOG_S3_SECRET_KEY"`
+	PublicURL string `yaml:"public_url" toml:"public_url" env:"BLOG_S3_PUBLIC_URL"`
+}
+
+type Limits struct {
+	MaxAvatarBytes      int64 `yaml:"max_avatar_bytes" toml:"max_avatar_bytes" env:"BLOG_LIMITS_MAX_AVATAR_BYTES"`
+	MaxMediaUploadBytes int64 `yaml:"max_media_upload_bytes" toml:"max_media_upload_bytes" env:"BLOG_LIMITS_MAX_MEDIA_UPLOAD_BYTES"`
+	MaxMediaChunkBytes  int64 `yaml:"max_media_chunk_bytes" toml:"max_media_chunk_bytes" env:"BLOG_LIMITS_MAX_MEDIA_CHUNK_BYTES"`
+	MaxMediaFileBytes   int64 `yaml:"max_media_file_bytes" toml:"max_media_file_bytes" env:"BLOG_LIMITS_MAX_MEDIA_FILE_BYTES"`
+	MediaQuotaBytes     int64 `yaml:"media_quota_bytes" toml:"media_quota_bytes" env:"BLOG_LIMITS_MEDIA_QUOTA_BYTES"`
+}
+
+// Duration понимает строки вида "15m" и "168h" во всех источниках.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			ReadTimeout:     Duration{15 * time.Second},
+			WriteTimeout:    Duration{60 * time.Second},
+			IdleTimeout:     Duration{120 * time.Second},
+			ShutdownTimeout: Duration{20 * time.Second},
+		},
+		Database: Database{
+			ConnectTimeout:     Duration{time.Minute},
+			SlowQueryThreshold: Duration{200 * time.Millisecond},
+		},
+		JWT: JWT{
+			AccessTTL:  Duration{15 * time.Minute},
+			RefreshTTL: Duration{7 * 24 * time.Hour},
+		},
+		Storage: Storage{
+			Driver:   "local",
+			LocalDir: "uploads",
+			S3:       S3{Region: "us-east-1"},
+		},
+		Limits: Limits{
+			MaxAvatarBytes:      5 << 20,
+			MaxMediaUploadBytes: 20 << 20,
+			MaxMediaChunkBytes:  8 << 20,
+			MaxMediaFileBytes:   200 << 20,
+			MediaQuotaBytes:     500 << 20,
+		},
+		Log: Log{Level: "info", Format: "json"},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "blog",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimit{
+			Backend:  "memory",
+			Login:    RateLimitPolicy{PerMinute: 10, Burst: 5, Key: "ip"},
+			Register: RateLimitPolicy{PerMinute: 3, Burst: 3, Key: "ip"},
+			Refresh:  RateLimitPolicy{PerMinute: 30, Burst: 10, Key: "ip"},
+			Upload:   RateLimitPolicy{PerMinute: 10, Burst: 5, Key: "user"},
+			API:      RateLimitPolicy{PerMinute: 300, Burst: 60, Key: "user"},
+		},
+		Mail: Mail{
+			Driver:  "log",
+			From:    "Blog <no-reply@localhost>",
+			Port:    587,
+			SiteURL: "http://localhost:8080",
+		},
+		Users: Users{
+			ErasureGrace: Duration{30 * 24 * time.Hour},
+			ExportTTL:    Duration{7 * 24 * time.Hour},
+		},
+		Audit: Audit{
+			Mode:          "sync",
+			BatchSize:     500,
+			FlushInterval: Duration{time.Second},
+			Retention:     Duration{400 * 24 * time.Hour},
+			ArchiveDir:    "audit-archive",
+		},
+	}
+}
+
+// Load разбирает флаги из args и собирает конфигурацию. Путь к файлу берётся
+// из -config или BLOG_CONFIG. Возвращает аргументы, оставшиеся после флагов.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	fields := collectFields(reflect.ValueOf(&cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("blog", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("BLOG_CONFIG"), "путь к файлу конфигурации (.yaml, .yml или .toml)")
+	overrides := map[string]string{}
+	for _, f := range fields {
+		name := f.flag
+		fs.Func(name, "переопределяет "+name+" (env "+f.env+")", func(v string) error {
+			overrides[name] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := f.set(v); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+	for _, f := range fields {
+		if v, ok := overrides[f.flag]; ok {
+			if err := f.set(v); err != nil {
+				return nil, nil, fmt.Errorf("-%s: %w", f.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr не задан")
+	check(c.Server.ReadTimeout.Duration > 0 && c.Server.WriteTimeout.Duration > 0 && c.Server.IdleTimeout.Duration > 0,
+		"server.read_timeout, write_timeout и idle_timeout должны быть больше нуля")
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdown_timeout должен быть больше нуля")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies: %q — не IP-адрес и не подсеть", proxy)
+	}
+	check(c.Database.DSN != "", "database.dsn не задан (BLOG_DATABASE_DSN)")
+	check(c.Database.ConnectTimeout.Duration >= 0, "database.connect_timeout не может быть отрицательным")
+	check(len(c.JWT.Secret) >= 16, "jwt.secret должен быть не короче 16 символов (BLOG_JWT_SECRET)")
+	check(c.JWT.AccessTTL.Duration > 0, "jwt.access_ttl должен быть больше нуля")
+	check(c.JWT.RefreshTTL.Duration > c.JWT.AccessTTL.Duration, "jwt.refresh_ttl должен быть больше jwt.access_ttl")
+
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.LocalDir != "", "storage.local_dir не задан")
+		check(len(c.Storage.SigningKey) >= 16, "storage.signing_key должен быть не короче 16 символов (BLOG_STORAGE_SIGNING_KEY)")
+	case "s3":
+		s3 := c.Storage.S3
+		check(s3.Endpoint != "" && s3.Bucket != "" && s3.AccessKey != "" && s3.SecretKey != "",
+			"для storage.driver = s3 нужны storage.s3.endpoint, bucket, access_key и secret_key")
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver: неизвестный драйвер %q (local или s3)", c.Storage.Driver))
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: неизвестный уровень %q", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format должен быть json или text")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.Endpoint != "", "tracing.endpoint не задан")
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: неизвестный экспортёр %q (none, stdout или otlp)", c.Tracing.Exporter))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio должен быть от 0 до 1")
+
+	check(c.RateLimit.Backend == "memory" || c.RateLimit.Backend == "postgres", "rate_limit.backend должен быть memory или postgres")
+	for name, p := range c.RateLimit.Policies() {
+		check(p.PerMinute >= 0, "rate_limit.%s.per_minute не может быть отрицательным", name)
+		check(p.PerMinute == 0 || p.Burst >= 1, "rate_limit.%s.burst должен быть не меньше 1", name)
+		check(p.Key == "ip" || p.Key == "user" || p.Key == "token", "rate_limit.%s.key должен быть ip, user или token", name)
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "smtp":
+		check(c.Mail.Host != "", "mail.host обязателен для драйвера smtp")
+		check(c.Mail.Port > 0, "mail.port должен быть больше нуля")
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver: неизвестный драйвер %q (log или smtp)", c.Mail.Driver))
+	}
+	check(c.Mail.From != "", "mail.from не задан")
+
+	check(c.Users.ErasureGrace.Duration >= 0, "users.erasure_grace не может быть отрицательным")
+	check(c.Users.ExportTTL.Duration > 0, "users.export_ttl должен быть больше нуля")
+	check(c.Audit.Mode == "sync" || c.Audit.Mode == "async", "audit.mode должен быть sync или async")
+	check(c.Audit.BatchSize > 0, "audit.batch_size должен быть больше нуля")
+	check(c.Audit.FlushInterval.Duration > 0, "audit.flush_interval должен быть больше нуля")
+	check(c.Audit.Retention.Duration >= 0, "audit.retention не может быть отрицательным")
+	check(c.Audit.Retention.Duration == 0 || c.Audit.ArchiveDir != "", "audit.archive_dir обязателен, если задан audit.retention")
+	sinkNames := map[string]bool{}
+	for i, sink := range c.Audit.Sinks {
+		check(sink.Name != "" && !sinkNames[sink.Name], "audit.sinks[%d]: name пустой или повторяется", i)
+		sinkNames[sink.Name] = true
+		switch sink.Type {
+		case "syslog":
+			check(sink.Network == "udp" || sink.Network == "tcp" || sink.Network == "tls",
+				"audit.sinks[%d]: network должен быть udp, tcp или tls", i)
+			check(sink.Address != "", "audit.sinks[%d]: не задан address", i)
+			check(sink.Facility >= 0 && sink.Facility <= 23, "audit.sinks[%d]: facility должен быть от 0 до 23", i)
+		case "webhook":
+			check(strings.HasPrefix(sink.URL, "http://") || strings.HasPrefix(sink.URL, "https://"),
+				"audit.sinks[%d]: url должен начинаться с http:// или https://", i)
+			check(len(sink.Secret) >= 16, "audit.sinks[%d]: secret должен быть не короче 16 символов", i)
+			check(sink.MaxRetries >= 0, "audit.sinks[%d]: max_retries не может быть отрицательным", i)
+		case "file":
+			check(sink.Path != "", "audit.sinks[%d]: не задан path", i)
+		default:
+			errs = append(errs, fmt.Errorf("audit.sinks[%d]: неизвестный тип %q (syslog, webhook или file)", i, sink.Type))
+		}
+	}
+	check(c.Audit.HMACKey == "" || len(c.Audit.HMACKey) >= 16, "audit.hmac_key должен быть не короче 16 символов (BLOG_AUDIT_HMAC_KEY)")
+
+	check(c.Limits.MaxAvatarBytes > 0, "limits.max_avatar_bytes должен быть больше нуля")
+	check(c.Limits.MaxMediaUploadBytes > 0, "limits.max_media_upload_bytes должен быть больше нуля")
+	check(c.Limits.MaxMediaChunkBytes > 0, "limits.max_media_chunk_bytes должен быть больше нуля")
+	check(c.Limits.MediaQuotaBytes > 0, "limits.media_quota_bytes должен быть больше нуля")
+	check(c.Limits.MaxMediaFileBytes >= c.Limits.MaxMediaUploadBytes,
+		"limits.max_media_file_bytes не может быть меньше limits.max_media_upload_bytes")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("некорректная конфигурация:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Policies возвращает политики по именам, которые используют маршруты.
+func (r RateLimit) Policies() map[string]RateLimitPolicy {
+	return map[string]RateLimitPolicy{
+		"login":    r.Login,
+		"register": r.Register,
+		"refresh":  r.Refresh,
+		"upload":   r.Upload,
+		"api":      r.API,
+	}
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать конфигурацию: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("неизвестный формат конфигурации %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка в %s: %w", path, err)
+	}
+	return nil
+}
+
+type field struct {
+	flag string
+	env  string
+	set  func(string) error
+}
+
+// collectFields обходит структуру и собирает все скалярные поля. Имя флага
+// строится из yaml-пути (jwt.access_ttl -> -jwt.access_ttl), переменная
+// окружения берётся из тега env или выводится из того же пути
+// (rate_limit.login.burst -> BLOG_RATE_LIMIT_LOGIN_BURST).
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		name := prefix + sf.Tag.Get("yaml")
+
+		// списки (audit.sinks) задаются только в файле конфигурации
+		if fv.Kind() == reflect.Slice {
+			continue
+		}
+		if _, isDuration := fv.Addr().Interface().(*Duration); fv.Kind() == reflect.Struct && !isDuration {
+			fields = append(fields, collectFields(fv, name+".")...)
+			continue
+		}
+
+		env := sf.Tag.Get("env")
+		if env == "" {
+			env = "BLOG_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+		}
+		fields = append(fields, field{flag: name, env: env, set: setter(fv)})
+	}
+	return fields
+}
+
+func setter(v reflect.Value) func(string) error {
+	return func(s string) error {
+		if d, ok := v.Addr().Interface().(*Duration); ok {
+			return d.UnmarshalText([]byte(s))
+		}
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(s)
+		case reflect.Int64, reflect.Int:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		default:
+			return fmt.Errorf("неподдерживаемый тип %s", v.Type())
+		}
+		return nil
+	}
+}
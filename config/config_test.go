@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequired задаёт обязательные значения, без которых Load не проходит Validate.
+func setRequired(t *testing.T) {
+	t.Setenv("BLOG_CONFIG", "")
+	t.Setenv("BLOG_DATABASE_DSN", "host=localhost")
+	t.Setenv("BLOG_JWT_SECRET", "0123456789abcdef")
+	t.Setenv("BLOG_STORAGE_SIGNING_KEY", "0123456789abcdef")
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setRequired(t)
+	path := writeConfig(t, "blog.yaml", `
+server:
+  addr: ":1001"
+  trusted_proxies: ["10.0.0.0/8"]
+jwt:
+  access_ttl: 5m
+limits:
+  max_avatar_bytes: 100
+  max_media_chunk_bytes: 100
+`)
+	t.Setenv("BLOG_CONFIG", path)
+	t.Setenv("BLOG_SERVER_ADDR", ":1002")
+	t.Setenv("BLOG_LIMITS_MAX_AVATAR_BYTES", "200")
+	// переменная без тега env выводится из пути
+	t.Setenv("BLOG_RATE_LIMIT_LOGIN_BURST", "7")
+
+	cfg, rest, err := Load([]string{"-server.addr", ":1003", "-jwt.refresh_ttl", "1h", "serve", "-x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := Default()
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"по умолчанию", cfg.Server.ReadTimeout.Duration, defaults.Server.ReadTimeout.Duration},
+		{"файл поверх умолчаний", cfg.JWT.AccessTTL.Duration, 5 * time.Minute},
+		{"список из файла", strings.Join(cfg.Server.TrustedProxies, ","), "10.0.0.0/8"},
+		{"только файл", cfg.Limits.MaxMediaChunkBytes, int64(100)},
+		{"env поверх файла", cfg.Limits.MaxAvatarBytes, int64(200)},
+		{"env по пути", cfg.RateLimit.Login.Burst, 7},
+		{"флаг поверх env и файла", cfg.Server.Addr, ":1003"},
+		{"флаг поверх умолчаний", cfg.JWT.RefreshTTL.Duration, time.Hour},
+		{"аргументы после флагов", strings.Join(rest, " "), "serve -x"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	setRequired(t)
+	path := writeConfig(t, "blog.toml", `
+[server]
+addr = ":2001"
+
+[log]
+level = "debug"
+`)
+	cfg, _, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":2001" || cfg.Log.Level != "debug" {
+		t.Errorf("из TOML: addr %q, log.level %q", cfg.Server.Addr, cfg.Log.Level)
+	}
+}
+
+func TestLoadRejectsUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+		args []string
+		want string
+	}{
+		{name: "поле в yaml", file: "blog.yaml", body: "server:\n  adr: \":1\"\n", want: "adr"},
+		{name: "раздел в yaml", file: "blog.yaml", body: "limit:\n  max_avatar_bytes: 1\n", want: "limit"},
+		{name: "поле в toml", file: "blog.toml", body: "[server]\nadr = \":1\"\n", want: "missing in the target struct"},
+		{name: "формат файла", file: "blog.json", body: "{}", want: "неизвестный формат"},
+		{name: "флаг", args: []string{"-server.adr", ":1"}, want: "server.adr"},
+		{name: "значение флага", args: []string{"-limits.max_avatar_bytes", "много"}, want: "-limits.max_avatar_bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			args := tt.args
+			if tt.file != "" {
+				args = []string{"-config", writeConfig(t, tt.file, tt.body)}
+			}
+			_, _, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка %v, want с %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBadEnv(t *testing.T) {
+	setRequired(t)
+	t.Setenv("BLOG_JWT_ACCESS_TTL", "долго")
+	if _, _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "BLOG_JWT_ACCESS_TTL") {
+		t.Errorf("ошибка %v, want с именем переменной", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		cfg := Default()
+		cfg.Database.DSN = "host=localhost"
+		cfg.JWT.Secret = "0123456789abcdef"
+		cfg.Storage.SigningKey = "0123456789abcdef"
+		return cfg
+	}
+	if cfg := valid(); cfg.Validate() != nil {
+		t.Fatalf("конфигурация по умолчанию с секретами не проходит проверку: %v", cfg.Validate())
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"нет dsn", func(c *Config) { c.Database.DSN = "" }, "database.dsn"},
+		{"короткий секрет", func(c *Config) { c.JWT.Secret = "short" }, "jwt.secret"},
+		{"refresh короче access", func(c *Config) { c.JWT.RefreshTTL = c.JWT.AccessTTL }, "jwt.refresh_ttl"},
+		{"прокси", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} }, "proxy.local"},
+		{"драйвер хранилища", func(c *Config) { c.Storage.Driver = "ftp" }, "storage.driver"},
+		{"s3 без ключей", func(c *Config) { c.Storage.Driver = "s3" }, "storage.s3"},
+		{"размер аватара", func(c *Config) { c.Limits.MaxAvatarBytes = 0 }, "limits.max_avatar_bytes"},
+		{"файл меньше загрузки", func(c *Config) { c.Limits.MaxMediaFileBytes = c.Limits.MaxMediaUploadBytes - 1 }, "limits.max_media_file_bytes"},
+		{"тип получателя аудита", func(c *Config) { c.Audit.Sinks = []AuditSink{{Name: "x", Type: "kafka"}} }, "audit.sinks[0]"},
+		{"ключ HMAC", func(c *Config) { c.Audit.HMACKey = "short" }, "audit.hmac_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка %v, want с %q", err, tt.want)
+			}
+		})
+	}
+
+	// все ошибки собираются сразу, а не по одной
+	cfg := valid()
+	cfg.Database.DSN = ""
+	cfg.JWT.Secret = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "database.dsn") || !strings.Contains(err.Error(), "jwt.secret") {
+		t.Errorf("ошибка %v, want обе причины", err)
+	}
+}
@@ -2,20 +2,24 @@ package dto
 
 import (
 	"Blog/models"
+	"encoding/json"
 	"time"
 )
 
 type AuditLogResponse struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"user_id"`
-	Action    string    `json:"action"`
-	Object    string    `json:"object"`
-	ObjectID  uint      `json:"object_id"`
-	Timestamp time.Time `json:"timestamp"`
+	ID            uint      `json:"id"`
+	UserID        uint      `json:"user_id"`
+	ActorNickname string    `json:"actor_nickname,omitempty"`
+	Action        string    `json:"action"`
+	Object        string    `json:"object"`
+	ObjectID      uint      `json:"object_id"`
+	Timestamp     time.Time `json:"timestamp"`
 
-	IP        string `json:"ip"`
-	UserAgent string `json:"user_agent"`
-	MetaData  string `json:"metadata"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	MetaData  json.RawMessage `json:"metadata"` // audit.Metadata; у старых записей — строка
+	// личные данные в записи стёрты после удаления пользователя
+	Redacted bool `json:"redacted,omitempty"`
 }
 
 func ToAuditLogResponse(log models.AuditLog) AuditLogResponse {
@@ -29,13 +33,72 @@ func ToAuditLogResponse(log models.AuditLog) AuditLogResponse {
 		IP:        log.IP,
 		UserAgent: log.UserAgent,
 		MetaData:  log.Metadata,
+		Redacted:  log.RedactedHash != "",
 	}
 }
 
-func ToAuditLogList(logs []models.AuditLog) []AuditLogResponse {
-	result := make([]AuditLogResponse, 0, len(logs))
-	for _, log := range logs {
-		result = append(result, ToAuditLogResponse(log))
+// AuditLogEntry — запись журнала вместе с ником того, кто выполнил действие.
+type AuditLogEntry struct {
+	models.AuditLog
+	ActorNickname string
+}
+
+func ToAuditLogList(entries []AuditLogEntry) []AuditLogResponse {
+	result := make([]AuditLogResponse, 0, len(entries))
+	for _, e := range entries {
+		resp := ToAuditLogResponse(e.AuditLog)
+		resp.ActorNickname = e.ActorNickname
+		result = append(result, resp)
+	}
+	return result
+}
+
+// SecurityEventResponse — событие в журнале своего аккаунта. IP и браузер
+// показываются только для своих действий и анонимных (неудачный вход), но
+// не для действий администраторов.
+type SecurityEventResponse struct {
+	ID        uint      `json:"id"`
+	Action    string    `json:"action"`
+	Timestamp time.Time `json:"timestamp"`
+	// кто выполнил: self — сам пользователь, admin — другой пользователь,
+	// system — консольная команда или фоновая задача, anonymous — без
+	// входа (например, неудачная попытка входа)
+	Actor     string   `json:"actor"`
+	IP        string   `json:"ip,omitempty"`
+	UserAgent string   `json:"user_agent,omitempty"`
+	Changed   []string `json:"changed,omitempty"` // изменённые поля профиля
+}
+
+func ToSecurityEventList(entries []AuditLogEntry, userID uint) []SecurityEventResponse {
+	result := make([]SecurityEventResponse, 0, len(entries))
+	for _, e := range entries {
+		event := SecurityEventResponse{
+			ID:        e.ID,
+			Action:    e.Action,
+			Timestamp: e.Timestamp,
+		}
+		var meta struct {
+			Source  string   `json:"source"`
+			Changed []string `json:"changed"`
+		}
+		if json.Unmarshal(e.Metadata, &meta) == nil {
+			event.Changed = meta.Changed
+		}
+
+		switch {
+		case e.UserID == userID:
+			event.Actor = "self"
+		case e.UserID == 0 && meta.Source != "":
+			event.Actor = "system"
+		case e.UserID == 0:
+			event.Actor = "anonymous"
+		default:
+			event.Actor = "admin"
+		}
+		if event.Actor == "self" || event.Actor == "anonymous" {
+			event.IP, event.UserAgent = e.IP, e.UserAgent
+		}
+		result = append(result, event)
 	}
 	return result
 }
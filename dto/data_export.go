@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"Blog/models"
+	"time"
+)
+
+type DataExportResponse struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status"` // pending, running, ready, failed или expired
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// подписанная ссылка на архив, только у готовой выгрузки; каждый запрос выдаёт новую
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func ToDataExportResponse(e models.DataExport, downloadURL string) DataExportResponse {
+	return DataExportResponse{
+		ID:          e.ID,
+		Status:      e.Status,
+		Size:        e.Size,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+		DownloadURL: downloadURL,
+	}
+}
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"Blog/filestore"
+	"Blog/models"
+	"time"
+)
+
+type CreateUploadInput struct {
+	FileName    string `json:"file_name" validate:"required,max=255"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size" validate:"required,gt=0"`
+}
+
+type MediaResponse struct {
+	ID           uint      `json:"id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func ToMediaResponse(m models.Media) MediaResponse {
+	resp := MediaResponse{
+		ID:          m.ID,
+		FileName:    m.FileName,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		URL:         filestore.Default.URL(m.Key),
+		Width:       m.Width,
+		Height:      m.Height,
+		CreatedAt:   m.CreatedAt,
+	}
+	if m.ThumbnailKey != "" {
+		resp.ThumbnailURL = filestore.Default.URL(m.ThumbnailKey)
+	}
+	return resp
+}
+
+func ToMediaList(media []models.Media) []MediaResponse {
+	result := make([]MediaResponse, 0, len(media))
+	for _, m := range media {
+		result = append(result, ToMediaResponse(m))
+	}
+	return result
+}
+
+type MediaUploadResponse struct {
+	ID        string    `json:"upload_id"`
+	FileName  string    `json:"file_name"`
+	Size      int64     `json:"size"`
+	Received  int64     `json:"received"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func ToMediaUploadResponse(u models.MediaUpload) MediaUploadResponse {
+	return MediaUploadResponse{
+		ID:        u.ID,
+		FileName:  u.FileName,
+		Size:      u.Size,
+		Received:  u.Received,
+		ExpiresAt: u.ExpiresAt,
+	}
+}
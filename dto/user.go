@@ -1,11 +1,18 @@
 package dto
 
-import "Blog/models"
+import (
+	"Blog/models"
+	"Blog/utils"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type RegisterInput struct {
 	Nickname string `json:"nickname" validate:"required,min=3"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=5"`
+	Language string `json:"language" validate:"omitempty,oneof=ru en kk"`
 }
 
 type LoginInput struct {
@@ -13,28 +20,88 @@ type LoginInput struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UpdateUserInput — частичное обновление: пустые поля не меняются.
 type UpdateUserInput struct {
-	Nickname string `json:"nickname" validate:"required"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"omitempty, min=5"`
+	Nickname string `json:"nickname" validate:"omitempty,min=3"`
+	Email    string `json:"email" validate:"omitempty,email"`
+	Password string `json:"password" validate:"omitempty,min=5"`
+	// Language — предпочитаемый язык; null не меняет его, "" сбрасывает
+	Language *string `json:"language"`
+}
+
+// UpdateRoleInput — смена роли администратором.
+type UpdateRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// ModerationInput — блокировка пользователя. Без until — бессрочно.
+type ModerationInput struct {
+	Reason string     `json:"reason" validate:"required,max=500"`
+	Until  *time.Time `json:"until" validate:"omitempty,gt"`
 }
 
 type UserResponse struct {
-	ID        uint   `json:"id"`
-	Nickname  string `json:"nickname"`
-	Email     string `json:"email"`
-	Role      string `json:"role"`
-	AvatarURL string `json:"avatar_url"`
+	ID          uint              `json:"id"`
+	Nickname    string            `json:"nickname"`
+	Email       string            `json:"email"`
+	Role        string            `json:"role"`
+	AvatarURL   string            `json:"avatar_url"`
+	Avatars     map[string]string `json:"avatars,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Status      string            `json:"status"`
+	StatusUntil *time.Time        `json:"status_until,omitempty"`
 }
 
 func ToUserResponse(u models.User) UserResponse {
-	return UserResponse{
+	resp := UserResponse{
 		ID:        u.ID,
 		Nickname:  u.Nickname,
 		Email:     u.Email,
 		Role:      u.Role,
 		AvatarURL: u.AvatarURL,
+		Avatars:   AvatarURLs(u.AvatarURL),
+		Language:  u.Language,
+		Status:    models.StatusActive,
+	}
+	// истёкшая блокировка показывается как active ещё до того, как её снимет фоновая задача
+	if u.Blocked(time.Now()) {
+		resp.Status = u.Status
+		resp.StatusUntil = u.StatusUntil
+	}
+	if u.AvatarURL == "" {
+		resp.AvatarURL = DefaultAvatarURL(u.ID)
+		resp.Avatars = DefaultAvatarURLs(u.ID)
+	}
+	return resp
+}
+
+// DefaultAvatarURL — сгенерированный аватар для пользователя без загруженного.
+func DefaultAvatarURL(userID uint) string {
+	return "/avatars/" + strconv.FormatUint(uint64(userID), 10) + ".png"
+}
+
+func DefaultAvatarURLs(userID uint) map[string]string {
+	base := DefaultAvatarURL(userID)
+	urls := make(map[string]string, len(utils.AvatarSizes))
+	for _, size := range utils.AvatarSizes {
+		urls[strconv.Itoa(size)] = base + "?size=" + strconv.Itoa(size)
+	}
+	return urls
+}
+
+// AvatarURLs возвращает ссылки на все размеры аватара, ключ — размер в пикселях.
+func AvatarURLs(avatarURL string) map[string]string {
+	largest := utils.AvatarSizes[len(utils.AvatarSizes)-1]
+	if !strings.HasSuffix(avatarURL, "/"+utils.AvatarFileName(largest)) {
+		return nil
+	}
+
+	dir := avatarURL[:strings.LastIndex(avatarURL, "/")]
+	urls := make(map[string]string, len(utils.AvatarSizes))
+	for _, size := range utils.AvatarSizes {
+		urls[strconv.Itoa(size)] = dir + "/" + utils.AvatarFileName(size)
 	}
+	return urls
 }
 
 type CreateUserInput struct {
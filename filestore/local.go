@@ -0,0 +1,147 @@
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey       = errors.New("некорректный ключ файла")
+	ErrInvalidSignature = errors.New("неверная подпись ссылки")
+	ErrLinkExpired      = errors.New("срок действия ссылки истёк")
+)
+
+const SignedPathPrefix = "/files/"
+
+// publicPrefixes — каталоги, файлы из которых раздаются по BaseURL без
+// подписи. Остальное (выгрузки данных, части загрузок) доступно только по
+// подписанной ссылке.
+var publicPrefixes = []string{"avatars/", "media/"}
+
+// IsPublic сообщает, можно ли отдавать файл key по прямой ссылке.
+func IsPublic(key string) bool {
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	// старые аватары лежат одним файлом в корне: avatar_<id>.<ext>
+	return !strings.Contains(key, "/") && strings.HasPrefix(key, "avatar_")
+}
+
+// Local хранит файлы на диске. Публичные файлы (IsPublic) раздаются по
+// BaseURL, приватные — по подписанной ссылке через SignedPathPrefix.
+type Local struct {
+	Root    string
+	BaseURL string
+	secret  []byte
+}
+
+func NewLocal(root, baseURL string, secret []byte) *Local {
+	return &Local{Root: root, BaseURL: strings.TrimSuffix(baseURL, "/"), secret: secret}
+}
+
+func (l *Local) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(clean)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.BaseURL + "/" + key
+}
+
+func (l *Local) SignedURL(key string, ttl time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", l.sign(key, expires))
+	return SignedPathPrefix + key + "?" + q.Encode(), nil
+}
+
+// Verify проверяет подпись и срок действия ссылки, выданной SignedURL.
+func (l *Local) Verify(key string, query url.Values) error {
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(l.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > unix {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package filestore
+
+import (
+	"Blog/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("файл не найден")
+
+// Store — хранилище загруженных файлов. Ключи имеют вид "avatars/1/abc/64.png".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL возвращает публичную ссылку на файл.
+	URL(key string) string
+	// SignedURL возвращает ссылку на приватный файл, которая перестаёт работать через ttl.
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+var Default Store
+
+func Init(cfg config.Storage) {
+	switch cfg.Driver {
+	case "local":
+		Default = withTracing(NewLocal(cfg.LocalDir, "/uploads", []byte(cfg.SigningKey)), "local")
+	case "s3":
+		store, err := NewS3(S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PublicURL: cfg.S3.PublicURL,
+		})
+		if err != nil {
+			panic("Ошибка настройки S3: " + err.Error())
+		}
+		Default = withTracing(store, "s3")
+	default:
+		panic(fmt.Sprintf("неизвестный драйвер хранилища: %q", cfg.Driver))
+	}
+}
+
+// LocalStore возвращает локальный драйвер, если используется он.
+func LocalStore() (*Local, bool) {
+	store := Default
+	if t, ok := store.(traced); ok {
+		store = t.Store
+	}
+	local, ok := store.(*Local)
+	return local, ok
+}
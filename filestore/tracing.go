@@ -0,0 +1,56 @@
+package filestore
+
+import (
+	"Blog/tracing"
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+)
+
+// traced оборачивает Store спанами вокруг сетевых и дисковых операций.
+type traced struct {
+	Store
+	driver string
+}
+
+func withTracing(s Store, driver string) Store {
+	return traced{Store: s, driver: driver}
+}
+
+func (t traced) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "filestore."+op, trace.WithAttributes(
+		attribute.String("filestore.driver", t.driver),
+		attribute.String("filestore.key", key),
+	))
+}
+
+func (t traced) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	ctx, span := t.start(ctx, "put", key)
+	defer span.End()
+	span.SetAttributes(attribute.Int64("filestore.size", size))
+	return record(span, t.Store.Put(ctx, key, r, size, contentType))
+}
+
+func (t traced) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, span := t.start(ctx, "get", key)
+	defer span.End()
+	rc, err := t.Store.Get(ctx, key)
+	return rc, record(span, err)
+}
+
+func (t traced) Delete(ctx context.Context, key string) error {
+	ctx, span := t.start(ctx, "delete", key)
+	defer span.End()
+	return record(span, t.Store.Delete(ctx, key))
+}
+
+func record(span trace.Span, err error) error {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -1,82 +1,229 @@
 package handlers
 
 import (
+	"Blog/apierr"
+	"Blog/audit"
 	"Blog/dto"
+	"Blog/i18n"
 	"Blog/models"
 	"Blog/storage"
 	"Blog/utils"
 	"encoding/csv"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
-	"net/http"
 	"strconv"
+	"time"
 )
 
-func GetAuditLogs(c *gin.Context) {
-	var logs []models.AuditLog
-	if err := storage.DB.Order("timestamp desc").Limit(100).Find(&logs).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось загрузить журнал")
+func RestoreUser(c *gin.Context) {
+	idParam := c.Param("id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.Error(apierr.InvalidID)
 		return
 	}
 
-	logResponses := dto.ToAuditLogList(logs)
+	var user models.User
+	if err := storage.DB.WithContext(c).Unscoped().First(&user, userID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
+		return
+	}
+
+	if user.DeletedAt.Valid == false {
+		c.Error(apierr.UserAlreadyActive)
+		return
+	}
+	if user.ErasedAt != nil {
+		c.Error(apierr.UserErased)
+		return
+	}
+
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// пока шёл запрос, данные могла стереть фоновая задача
+		res := tx.Unscoped().Model(&models.User{}).Where("id = ? AND deleted_at IS NOT NULL AND erased_at IS NULL", user.ID).
+			Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apierr.UserErased
+		}
+		return utils.LogAudit(c, tx, "undelete_user", "user", user.ID, audit.Metadata{})
+	})
+	if errors.Is(err, apierr.UserErased) {
+		c.Error(apierr.UserErased)
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
 
 	utils.RespondOK(c, gin.H{
-		"logs": logResponses,
+		"message": i18n.T(c, "messages.user_restored"),
 	})
 }
 
-func RestoreUser(c *gin.Context) {
-	idParam := c.Param("id")
-	userID, err := strconv.Atoi(idParam)
+// moderationTarget разбирает :id и загружает пользователя, над которым
+// администратор выполняет действие. Действовать над собой нельзя: так
+// единственный администратор не лишит себя доступа по ошибке.
+func moderationTarget(c *gin.Context) (models.User, error) {
+	var user models.User
+	targetID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Некорректный ID")
+		return user, apierr.InvalidID
+	}
+	if uint(targetID) == c.GetUint("user_id") {
+		return user, apierr.CannotModerateSelf
+	}
+	if err := storage.DB.WithContext(c).First(&user, targetID).Error; err != nil {
+		return user, apierr.UserNotFound
+	}
+	return user, nil
+}
+
+// saveModeration сохраняет роль и блокировку пользователя и запись аудита с
+// изменёнными полями. Остальные колонки не трогаются, а роль и статус
+// должны совпадать с before: если их успел поменять другой администратор
+// или фоновая задача, возвращается apierr.ModerationConflict. Возвращает
+// ошибку API, готовую для c.Error.
+func saveModeration(c *gin.Context, action string, before, user models.User) error {
+	err := storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.User{}).Where("id = ? AND role = ? AND status = ?", user.ID, before.Role, before.Status).
+			Updates(map[string]interface{}{
+				"role":          user.Role,
+				"status":        user.Status,
+				"status_reason": user.StatusReason,
+				"status_until":  user.StatusUntil,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apierr.ModerationConflict
+		}
+		return utils.LogAudit(c, tx, action, "user", user.ID, audit.Metadata{Changes: audit.Diff(before, user)})
+	})
+	if err != nil && !errors.Is(err, apierr.ModerationConflict) {
+		return apierr.Internal.Wrap(err)
+	}
+	return err
+}
+
+func ChangeUserRole(c *gin.Context) {
+	user, err := moderationTarget(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	var user models.User
-	if err := storage.DB.Unscoped().First(&user, userID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	var input dto.UpdateRoleInput
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if user.DeletedAt.Valid == false {
-		utils.RespondError(c, http.StatusBadRequest, "Пользователь уже активен")
+	if user.Role != input.Role {
+		before := user
+		user.Role = input.Role
+		if err := saveModeration(c, "change_role", before, user); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	utils.RespondOK(c, gin.H{
+		"user": dto.ToUserResponse(user),
+	})
+}
+
+func SuspendUser(c *gin.Context) {
+	blockUser(c, models.StatusSuspended, "suspend_user")
+}
+
+func BanUser(c *gin.Context) {
+	blockUser(c, models.StatusBanned, "ban_user")
+}
+
+// blockUser ставит блокировку. Повторный вызов заменяет причину и срок
+// действующей блокировки, поэтому так же продлевают или сокращают её.
+func blockUser(c *gin.Context, status, action string) {
+	user, err := moderationTarget(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	user.DeletedAt = gorm.DeletedAt{}
-	if err := storage.DB.Unscoped().Save(&user).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось восстановить пользователя")
+	var input dto.ModerationInput
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
-	utils.LogAudit(c, "undelete_user", "user", user.ID, "")
+	before := user
+	user.Status = status
+	user.StatusReason = input.Reason
+	user.StatusUntil = nil
+	if input.Until != nil {
+		until := input.Until.UTC()
+		user.StatusUntil = &until
+	}
+	if err := saveModeration(c, action, before, user); err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"user": dto.ToUserResponse(user),
+	})
+}
+
+func ReinstateUser(c *gin.Context) {
+	user, err := moderationTarget(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !user.Blocked(time.Now()) {
+		c.Error(apierr.UserNotBlocked)
+		return
+	}
+
+	before := user
+	user.Status = models.StatusActive
+	user.StatusReason = ""
+	user.StatusUntil = nil
+	if err := saveModeration(c, "reinstate_user", before, user); err != nil {
+		c.Error(err)
+		return
+	}
 
 	utils.RespondOK(c, gin.H{
-		"message": "Пользователь восстановлен",
+		"user": dto.ToUserResponse(user),
 	})
 }
 
 func ExportUsersCSV(c *gin.Context) {
 	var users []models.User
-	query := storage.DB.Model(&models.User{})
+	query := storage.DB.WithContext(c).Model(&models.User{})
 	if search := c.Query("search"); search != "" {
-		term := "%" + search + "%"
-		query = query.Where("nickname ILIKE ? OR email ILIKE ?", term, term)
+		term := utils.ContainsPattern(search)
+		query = query.Where(`nickname ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\'`, term, term)
 	}
 	if role := c.Query("role"); role != "" {
 		query = query.Where("role = ?", role)
 	}
 	if email := c.Query("email"); email != "" {
-		query = query.Where("email ILIKE ?", "%"+email+"%")
+		query = query.Where(`email ILIKE ? ESCAPE '\'`, utils.ContainsPattern(email))
 	}
 	if nickname := c.Query("nickname"); nickname != "" {
-		query = query.Where("nickname ILIKE ?", "%"+nickname+"%")
+		query = query.Where(`nickname ILIKE ? ESCAPE '\'`, utils.ContainsPattern(nickname))
 	}
 
 	if err := query.Find(&users).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось получить пользователя")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/dto"
+	"Blog/logging"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// GetAuditLogs отдаёт журнал от новых записей к старым. Следующая страница
+// запрашивается с cursor из ответа; курсор — позиция (timestamp, id)
+// последней записи, поэтому новые записи не сдвигают страницы.
+func GetAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries, nextCursor, err := pageAuditLogs(c, auditQuery(c).Scopes(filter))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"logs":        dto.ToAuditLogList(entries),
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetSecurityLog — журнал событий своего аккаунта: свои действия и
+// действия над своим профилем, включая неудачные попытки входа.
+func GetSecurityLog(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	query := auditQuery(c).
+		Where("audit_logs.user_id = ? OR (audit_logs.object = ? AND audit_logs.object_id = ?)", userID, "user", userID)
+	if s := c.Query("action"); s != "" {
+		query = query.Where("audit_logs.action IN ?", strings.Split(s, ","))
+	}
+
+	entries, nextCursor, err := pageAuditLogs(c, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"events":      dto.ToSecurityEventList(entries, userID),
+		"next_cursor": nextCursor,
+	})
+}
+
+// pageAuditLogs читает страницу журнала по параметрам cursor и limit.
+func pageAuditLogs(c *gin.Context, query *gorm.DB) ([]dto.AuditLogEntry, string, error) {
+	limit := defaultAuditPageSize
+	if s := c.Query("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return nil, "", apierr.InvalidQuery.WithDetail("param", "limit")
+		}
+		limit = min(limit, maxAuditPageSize)
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		ts, id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, "", apierr.InvalidCursor.Wrap(err)
+		}
+		query = query.Where("(audit_logs.timestamp, audit_logs.id) < (?, ?)", ts, id)
+	}
+
+	var entries []dto.AuditLogEntry
+	if err := query.Order("audit_logs.timestamp DESC, audit_logs.id DESC").Limit(limit + 1).Scan(&entries).Error; err != nil {
+		return nil, "", apierr.Internal.Wrap(err)
+	}
+
+	var nextCursor string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		nextCursor = encodeAuditCursor(last.Timestamp, last.ID)
+	}
+	return entries, nextCursor, nil
+}
+
+// VerifyAuditLogs пересчитывает цепочку хешей всего журнала. Разрыв — не
+// ошибка запроса: ответ 200 с valid=false и первым сломанным звеном.
+func VerifyAuditLogs(c *gin.Context) {
+	report, err := audit.Verify(c, storage.DB)
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	utils.RespondOK(c, gin.H{"valid": report.Valid(), "report": report})
+}
+
+// ExportAuditLogs отдаёт отфильтрованный журнал целиком, от старых записей
+// к новым, в CSV или NDJSON (format). Записи читаются из базы потоком.
+func ExportAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	w, ok := audit.NewWriter(c.Writer, format)
+	if !ok {
+		c.Error(apierr.InvalidQuery.WithDetail("param", "format"))
+		return
+	}
+
+	// выгрузка журнала сама по себе событие для журнала
+	details := audit.Details("format", format, "query", c.Request.URL.RawQuery)
+	if err := utils.LogAudit(c, storage.DB, "export_audit_logs", "audit_log", 0, details); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	query := storage.DB.WithContext(c).Model(&models.AuditLog{}).Scopes(filter).
+		Order("audit_logs.timestamp, audit_logs.id")
+	rows, err := query.Rows()
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", audit.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="audit_logs.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	// заголовки уже отправлены: ошибку можно только записать в лог
+	for rows.Next() {
+		var entry models.AuditLog
+		if err = query.ScanRows(rows, &entry); err != nil {
+			break
+		}
+		if err = w.Write(entry); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Выгрузка журнала аудита прервана", "error", err)
+	}
+}
+
+// auditQuery — записи журнала с ником автора; удалённые пользователи тоже
+// подтягиваются, иначе пропадёт автор самых интересных записей.
+func auditQuery(c *gin.Context) *gorm.DB {
+	return storage.DB.WithContext(c).Model(&models.AuditLog{}).
+		Select("audit_logs.*, users.nickname AS actor_nickname").
+		Joins("LEFT JOIN users ON users.id = audit_logs.user_id")
+}
+
+// parseAuditFilter разбирает фильтры журнала из строки запроса:
+// user_id, action (через запятую), object, object_id, ip, from, to (RFC 3339)
+// и changed — изменённые поля через запятую, подходит запись с любым из них.
+func parseAuditFilter(c *gin.Context) (func(*gorm.DB) *gorm.DB, error) {
+	var conds []func(*gorm.DB) *gorm.DB
+	add := func(query string, args ...interface{}) {
+		conds = append(conds, func(db *gorm.DB) *gorm.DB { return db.Where(query, args...) })
+	}
+
+	for _, name := range []string{"user_id", "object_id"} {
+		if s := c.Query(name); s != "" {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return nil, apierr.InvalidQuery.WithDetail("param", name)
+			}
+			add("audit_logs."+name+" = ?", id)
+		}
+	}
+	if s := c.Query("action"); s != "" {
+		add("audit_logs.action IN ?", strings.Split(s, ","))
+	}
+	if s := c.Query("changed"); s != "" {
+		fields := strings.Split(s, ",")
+		ors := make([]string, len(fields))
+		args := make([]interface{}, len(fields))
+		for i, field := range fields {
+			ors[i] = "audit_logs.metadata @> ?"
+			value, _ := json.Marshal(audit.Metadata{Changed: []string{field}})
+			args[i] = string(value)
+		}
+		add("("+strings.Join(ors, " OR ")+")", args...)
+	}
+	if s := c.Query("object"); s != "" {
+		add("audit_logs.object = ?", s)
+	}
+	if s := c.Query("ip"); s != "" {
+		add("audit_logs.ip = ?", s)
+	}
+	for name, op := range map[string]string{"from": ">=", "to": "<"} {
+		if s := c.Query(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, apierr.InvalidQuery.WithDetail("param", name)
+			}
+			add("audit_logs.timestamp "+op+" ?", t)
+		}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		for _, cond := range conds {
+			db = cond(db)
+		}
+		return db
+	}, nil
+}
+
+func encodeAuditCursor(ts time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", ts.UnixMicro(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAuditCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	tsPart, idPart, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("курсор без разделителя")
+	}
+	micros, err := strconv.ParseInt(tsPart, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.UnixMicro(micros), uint(id), nil
+}
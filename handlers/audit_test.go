@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestAuditCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 891234000, time.FixedZone("", 6*3600))
+	gotTS, gotID, err := decodeAuditCursor(encodeAuditCursor(ts, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotTS.Equal(ts) || gotID != 42 {
+		t.Errorf("получили (%v, %d), want (%v, 42)", gotTS, gotID, ts)
+	}
+
+	// наносекунды курсор не хранит: Postgres всё равно держит микросекунды
+	gotTS, _, _ = decodeAuditCursor(encodeAuditCursor(ts.Add(999), 1))
+	if !gotTS.Equal(ts) {
+		t.Errorf("время с наносекундами: %v", gotTS)
+	}
+}
+
+func TestDecodeAuditCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{
+		"не base64",
+		base64.RawURLEncoding.EncodeToString([]byte("123")),
+		base64.RawURLEncoding.EncodeToString([]byte("abc:1")),
+		base64.RawURLEncoding.EncodeToString([]byte("123:-1")),
+	} {
+		if _, _, err := decodeAuditCursor(cursor); err == nil {
+			t.Errorf("курсор %q принят", cursor)
+		}
+	}
+}
@@ -1,38 +1,49 @@
 package handlers
 
 import (
+	"Blog/apierr"
+	"Blog/audit"
 	"Blog/dto"
+	"Blog/i18n"
+	"Blog/logging"
+	"Blog/mail"
+	"Blog/metrics"
 	"Blog/models"
 	"Blog/storage"
 	"Blog/utils"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"net/http"
+	"gorm.io/gorm"
 	"strings"
 )
 
-func Register(c *gin.Context) {
-	var input dto.RegisterInput
+// Auth — обработчики входа, регистрации и обновления токенов.
+type Auth struct {
+	tokens *utils.Tokens
+}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Неверный JSON")
-		return
-	}
+func NewAuth(tokens *utils.Tokens) *Auth {
+	return &Auth{tokens: tokens}
+}
 
-	if err := validate.Struct(input); err != nil {
-		errors := utils.FormatValidationError(err)
-		utils.RespondError(c, http.StatusBadRequest, errors)
+func (a *Auth) Register(c *gin.Context) {
+	var input dto.RegisterInput
+
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var existing models.User
-	if err := storage.DB.Where("email = ?", input.Email).First(&existing).Error; err == nil {
-		utils.RespondError(c, http.StatusConflict, "Пользователь с таким email уже существует")
+	if err := storage.DB.WithContext(c).Where("email = ?", input.Email).First(&existing).Error; err == nil {
+		c.Error(apierr.EmailTaken)
 		return
 	}
 
-	hashed, err := utils.HashPassword(input.Password)
+	hashed, err := utils.HashPassword(c, input.Password)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка хеширования пароля")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -40,33 +51,46 @@ func Register(c *gin.Context) {
 		Nickname: input.Nickname,
 		Email:    input.Email,
 		Password: hashed,
+		Language: input.Language,
+	}
+	if user.Language == "" {
+		user.Language = i18n.FromContext(c)
 	}
 
-	if err := storage.DB.Create(&user).Error; err != nil {
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return auditAuth(c, tx, "register", user.ID, audit.Details("language", user.Language))
+	})
+	if err != nil {
 		// Проверка на дубликат по email
 		if strings.Contains(err.Error(), "duplicate key value") &&
 			strings.Contains(err.Error(), "users_email_key") {
-			utils.RespondError(c, http.StatusConflict, "Email уже используется")
+			c.Error(apierr.EmailTaken)
 			return
 		}
 
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка при создании пользователя")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	accessToken, err := utils.GenerateJWT(user.ID)
+	accessToken, err := a.tokens.GenerateJWT(user.ID)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID)
+	refreshToken, err := a.tokens.GenerateRefreshToken(user.ID)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "/", "", true, true)
+	c.SetCookie("refresh_token", refreshToken, int(a.tokens.RefreshTTL().Seconds()), "/", "", true, true)
+
+	metrics.Registrations.Inc()
+	go sendWelcomeEmail(context.WithoutCancel(c.Request.Context()), user)
 
 	utils.RespondCreated(c, gin.H{
 		"user":         dto.ToUserResponse(user),
@@ -74,83 +98,164 @@ func Register(c *gin.Context) {
 	})
 }
 
-func Login(c *gin.Context) {
+// sendWelcomeEmail отправляется в фоне: ответ на регистрацию не должен ждать SMTP.
+func sendWelcomeEmail(ctx context.Context, user models.User) {
+	err := mail.SendTemplate(ctx, user.Email, user.Language, "welcome", map[string]any{
+		"Nickname": user.Nickname,
+		"Email":    user.Email,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Не удалось отправить приветственное письмо", "user_id", user.ID, "error", err)
+	}
+}
+
+func (a *Auth) Login(c *gin.Context) {
 	var input dto.LoginInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.RespondError(c, http.StatusBadGateway, "Неверный JSON")
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := validate.Struct(input); err != nil {
-		errors := utils.FormatValidationError(err)
-		utils.RespondError(c, http.StatusBadRequest, errors)
+	var user models.User
+
+	if err := storage.DB.WithContext(c).Where("email = ?", input.Email).First(&user).Error; err != nil {
+		metrics.Logins.WithLabelValues(metrics.Result(false)).Inc()
+		auditAuthFailure(c, "login_failed", 0, audit.Details("reason", "unknown_email"))
+		c.Error(apierr.InvalidCredentials)
 		return
 	}
 
-	var user models.User
+	if !utils.CheckPasswordHash(c, input.Password, user.Password) {
+		metrics.Logins.WithLabelValues(metrics.Result(false)).Inc()
+		auditAuthFailure(c, "login_failed", user.ID, audit.Details("reason", "wrong_password"))
+		c.Error(apierr.InvalidCredentials)
+		return
+	}
 
-	if err := storage.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		utils.RespondError(c, http.StatusUnauthorized, "Пользователь не найден")
+	// о блокировке сообщаем только после проверки пароля, иначе по ответу
+	// можно узнать статус чужого аккаунта
+	if err := utils.AccountBlocked(user); err != nil {
+		metrics.Logins.WithLabelValues(metrics.Result(false)).Inc()
+		auditAuthFailure(c, "login_failed", user.ID, audit.Details("reason", user.Status))
+		c.Error(err)
 		return
 	}
 
-	if !utils.CheckPasswordHash(input.Password, user.Password) {
-		utils.RespondError(c, http.StatusUnauthorized, "Неверный пароль")
+	if err := auditAuth(c, storage.DB, "login", user.ID, audit.Metadata{}); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	accessToken, err := utils.GenerateJWT(user.ID)
+	accessToken, err := a.tokens.GenerateJWT(user.ID)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID)
+	refreshToken, err := a.tokens.GenerateRefreshToken(user.ID)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "/", "", true, true)
+	c.SetCookie("refresh_token", refreshToken, int(a.tokens.RefreshTTL().Seconds()), "/", "", true, true)
 
 	user.Password = ""
 
+	metrics.Logins.WithLabelValues(metrics.Result(true)).Inc()
+
 	utils.RespondOK(c, gin.H{
 		"user":         dto.ToUserResponse(user),
 		"access_token": accessToken,
 	})
 }
 
-func RefreshToken(c *gin.Context) {
+func (a *Auth) RefreshToken(c *gin.Context) {
 
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
-		utils.RespondError(c, http.StatusUnauthorized, "Нет refresh токена")
+		metrics.TokenRefreshes.WithLabelValues(metrics.Result(false)).Inc()
+		c.Error(apierr.RefreshTokenRequired)
 		return
 	}
 
-	userID, err := utils.ParseRefreshToken(refreshToken)
+	userID, err := a.tokens.ParseRefreshToken(refreshToken)
 	if err != nil {
-		utils.RespondError(c, http.StatusUnauthorized, err.Error())
+		metrics.TokenRefreshes.WithLabelValues(metrics.Result(false)).Inc()
+		auditAuthFailure(c, "token_refresh_failed", 0, audit.Details("reason", "invalid_token"))
+		c.Error(apierr.InvalidRefreshToken.Wrap(err))
 		return
 	}
 
-	newAccessToken, err := utils.GenerateJWT(userID)
+	var user models.User
+	if err := storage.DB.WithContext(c).First(&user, userID).Error; err != nil {
+		metrics.TokenRefreshes.WithLabelValues(metrics.Result(false)).Inc()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apierr.AccountNotFound)
+		} else {
+			c.Error(apierr.Internal.Wrap(err))
+		}
+		return
+	}
+	if err := utils.AccountBlocked(user); err != nil {
+		metrics.TokenRefreshes.WithLabelValues(metrics.Result(false)).Inc()
+		auditAuthFailure(c, "token_refresh_failed", userID, audit.Details("reason", user.Status))
+		c.Error(err)
+		return
+	}
+
+	if err := auditAuth(c, storage.DB, "token_refresh", userID, audit.Metadata{}); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	newAccessToken, err := a.tokens.GenerateJWT(userID)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось выдать новый токен")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
+	metrics.TokenRefreshes.WithLabelValues(metrics.Result(true)).Inc()
+
 	utils.RespondOK(c, gin.H{
 		"access_token": newAccessToken,
 	})
 }
 
-func Logout(c *gin.Context) {
+func (a *Auth) Logout(c *gin.Context) {
 	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
 
+	// без действующего refresh токена неизвестно, кто выходит, и записывать нечего
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil {
+		if userID, err := a.tokens.ParseRefreshToken(refreshToken); err == nil {
+			if err := auditAuth(c, storage.DB, "logout", userID, audit.Metadata{}); err != nil {
+				c.Error(apierr.Internal.Wrap(err))
+				return
+			}
+		}
+	}
+
 	utils.RespondOK(c, gin.H{
-		"message": "Вы вышли из системы",
+		"message": i18n.T(c, "messages.logged_out"),
 	})
 }
+
+// auditAuth записывает событие входа от имени userID: токена в запросе ещё
+// нет, поэтому автор берётся не из контекста.
+func auditAuth(c *gin.Context, tx *gorm.DB, action string, userID uint, metadata audit.Metadata) error {
+	entry := utils.AuditEntry(c, action, "user", userID, metadata)
+	entry.UserID = userID
+	return utils.WriteAudit(c, tx, entry)
+}
+
+// auditAuthFailure записывает неудачную попытку от анонимного автора.
+// objectID — аккаунт, к которому пытались войти, если он известен. Ответ
+// всё равно ошибка, поэтому сбой журнала только пишется в лог.
+func auditAuthFailure(c *gin.Context, action string, objectID uint, metadata audit.Metadata) {
+	entry := utils.AuditEntry(c, action, "user", objectID, metadata)
+	entry.UserID = 0
+	if err := utils.WriteAudit(c, storage.DB, entry); err != nil {
+		logging.FromContext(c).Error("Не удалось записать в журнал аудита", "action", action, "error", err)
+	}
+}
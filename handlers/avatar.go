@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// версия алгоритма входит в ETag, чтобы при его изменении кэши сбросились
+const identiconVersion = "v1"
+
+// GetDefaultAvatar отдаёт сгенерированный аватар для пользователей без загруженного.
+func GetDefaultAvatar(c *gin.Context) {
+	idStr, ok := strings.CutSuffix(c.Param("file"), ".png")
+	if !ok {
+		c.Error(apierr.AvatarNotFound)
+		return
+	}
+	userID, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.Error(apierr.InvalidID)
+		return
+	}
+
+	size := utils.AvatarSizes[len(utils.AvatarSizes)-1]
+	if s := c.Query("size"); s != "" {
+		size, err = strconv.Atoi(s)
+		if err != nil || !slices.Contains(utils.AvatarSizes, size) {
+			c.Error(apierr.InvalidSize)
+			return
+		}
+	}
+
+	seed := fmt.Sprintf("user:%d", userID)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", identiconVersion, seed, size)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=604800")
+	if match := c.GetHeader("If-None-Match"); match == etag || match == "*" {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := utils.EncodePNG(&buf, utils.Identicon([]byte(seed), size)); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	c.Data(http.StatusOK, utils.AvatarContentType, buf.Bytes())
+}
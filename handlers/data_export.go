@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/dto"
+	"Blog/filestore"
+	"Blog/i18n"
+	"Blog/logging"
+	"Blog/mail"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxExportLinkTTL — S3 не выдаёт подписанные ссылки дольше чем на 7 дней.
+const maxExportLinkTTL = 7 * 24 * time.Hour
+
+var errExportCancelled = errors.New("выгрузка данных отменена")
+
+// exportRequests будит задачу сборки выгрузок, чтобы не ждать её таймера.
+var exportRequests = make(chan struct{}, 1)
+
+// ExportRequests — сигнал о новой выгрузке для jobs.BuildDataExports.
+func ExportRequests() <-chan struct{} {
+	return exportRequests
+}
+
+// RequestDataExport ставит в очередь выгрузку всех данных пользователя.
+// Пока предыдущая выгрузка не собрана, повторный запрос возвращает её.
+func RequestDataExport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var export models.DataExport
+	err := storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND status IN ?", userID, []string{models.ExportPending, models.ExportRunning}).
+			Limit(1).Find(&export)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+
+		export = models.DataExport{UserID: userID, Status: models.ExportPending}
+		if err := tx.Create(&export).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "request_data_export", "user", userID, audit.Details("export_id", export.ID))
+	})
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	select {
+	case exportRequests <- struct{}{}:
+	default:
+	}
+
+	utils.RespondAccepted(c, gin.H{
+		"export": dto.ToDataExportResponse(export, ""),
+	})
+}
+
+// GetDataExport отдаёт последнюю выгрузку пользователя и, если архив готов,
+// свежую подписанную ссылку на него.
+func GetDataExport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var export models.DataExport
+	if err := storage.DB.WithContext(c).Where("user_id = ?", userID).Order("id DESC").First(&export).Error; err != nil {
+		c.Error(apierr.ExportNotFound)
+		return
+	}
+
+	link, err := dataExportLink(export)
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"export": dto.ToDataExportResponse(export, link),
+	})
+}
+
+// dataExportLink подписывает ссылку на архив до конца срока его хранения.
+// Для не готовой или истёкшей выгрузки возвращает пустую строку.
+func dataExportLink(export models.DataExport) (string, error) {
+	if export.Status != models.ExportReady || export.ExpiresAt == nil {
+		return "", nil
+	}
+	ttl := time.Until(*export.ExpiresAt)
+	if ttl <= 0 {
+		return "", nil
+	}
+	return filestore.Default.SignedURL(export.Key, min(ttl, maxExportLinkTTL))
+}
+
+// BuildDataExport собирает ZIP-архив с данными пользователя, кладёт его в
+// хранилище на срок ttl и отправляет письмо со ссылкой. Архив приватный:
+// скачать его можно только по подписанной ссылке (см. filestore.IsPublic).
+// sessionTTL — срок действия refresh токена: по нему в архив попадают
+// сессии, которые ещё действуют.
+func BuildDataExport(ctx context.Context, export models.DataExport, ttl, sessionTTL time.Duration) error {
+	var user models.User
+	if err := storage.DB.WithContext(ctx).First(&user, export.UserID).Error; err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	now := time.Now().UTC()
+	zw := zip.NewWriter(tmp)
+	if err := writeDataExport(ctx, zw, user, now, sessionTTL); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/%s.zip", user.ID, utils.RandomToken(16))
+	if err := filestore.Default.Put(ctx, key, tmp, size, "application/zip"); err != nil {
+		return err
+	}
+
+	expiresAt := now.Add(ttl)
+	export.Status = models.ExportReady
+	export.Key = key
+	export.Size = size
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	err = storage.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.DataExport{}).Where("id = ? AND status = ?", export.ID, models.ExportRunning).
+			Updates(map[string]interface{}{
+				"status":       export.Status,
+				"key":          key,
+				"size":         size,
+				"completed_at": now,
+				"expires_at":   expiresAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		// пока архив собирался, данные пользователя могли стереть вместе с выгрузкой
+		if res.RowsAffected == 0 {
+			return errExportCancelled
+		}
+		return utils.WriteAudit(ctx, tx, utils.SystemAuditEntry("job", "data_export_ready", "user", user.ID,
+			audit.Details("export_id", export.ID, "size", size)))
+	})
+	if err != nil {
+		filestore.Default.Delete(ctx, key)
+		return err
+	}
+
+	sendDataExportEmail(ctx, user, export)
+	return nil
+}
+
+func sendDataExportEmail(ctx context.Context, user models.User, export models.DataExport) {
+	link, err := dataExportLink(export)
+	if err == nil {
+		err = mail.SendTemplate(ctx, user.Email, user.Language, "data_export_ready", map[string]any{
+			"Nickname":  user.Nickname,
+			"URL":       mail.AbsoluteURL(link),
+			"ExpiresAt": export.ExpiresAt.Format("2006-01-02 15:04 UTC"),
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Не удалось отправить письмо о выгрузке данных", "user_id", user.ID, "error", err)
+	}
+}
+
+// writeDataExport пишет в архив всё, что хранится о пользователе. Постов и
+// комментариев в сервисе пока нет; их Markdown-файлы добавятся сюда вместе
+// с моделями.
+func writeDataExport(ctx context.Context, zw *zip.Writer, user models.User, now time.Time, sessionTTL time.Duration) error {
+	lang := user.Language
+	if !i18n.IsSupported(lang) {
+		lang = i18n.Default
+	}
+	if err := writeZipFile(zw, "README.txt", strings.NewReader(i18n.Translate(lang, "export.readme", now.Format(time.RFC3339)))); err != nil {
+		return err
+	}
+
+	profile := struct {
+		dto.UserResponse
+		RegisteredAt time.Time `json:"registered_at"`
+	}{dto.ToUserResponse(user), user.RegisteredAt}
+	if err := writeZipJSON(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	for _, key := range avatarKeys(user) {
+		if err := copyToZip(ctx, zw, "avatar/"+path.Base(key), key); err != nil {
+			return err
+		}
+	}
+
+	var media []models.Media
+	if err := storage.DB.WithContext(ctx).Where("user_id = ?", user.ID).Order("id").Find(&media).Error; err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "media.json", dto.ToMediaList(media)); err != nil {
+		return err
+	}
+	for _, m := range media {
+		if err := copyToZip(ctx, zw, fmt.Sprintf("media/%d-%s", m.ID, path.Base("/"+m.FileName)), m.Key); err != nil {
+			return err
+		}
+	}
+
+	var entries []dto.AuditLogEntry
+	var batch []models.AuditLog
+	err := storage.DB.WithContext(ctx).
+		Where("user_id = ? OR (object = ? AND object_id = ?)", user.ID, "user", user.ID).
+		FindInBatches(&batch, 1000, func(*gorm.DB, int) error {
+			for _, e := range batch {
+				entries = append(entries, dto.AuditLogEntry{AuditLog: e})
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "security_log.json", dto.ToSecurityEventList(entries, user.ID)); err != nil {
+		return err
+	}
+
+	return writeZipJSON(zw, "sessions.json", dataExportSessions(entries, user.ID, now, sessionTTL))
+}
+
+// dataExportSessions — входы, после которых refresh токен ещё может
+// действовать. Сами токены не хранятся, поэтому сессией считается вход.
+func dataExportSessions(entries []dto.AuditLogEntry, userID uint, now time.Time, ttl time.Duration) []gin.H {
+	sessions := []gin.H{}
+	for _, e := range entries {
+		if e.UserID != userID || (e.Action != "login" && e.Action != "register") || now.Sub(e.Timestamp) > ttl {
+			continue
+		}
+		sessions = append(sessions, gin.H{
+			"signed_in_at": e.Timestamp.UTC(),
+			"expires_at":   e.Timestamp.Add(ttl).UTC(),
+			"ip":           e.IP,
+			"user_agent":   e.UserAgent,
+		})
+	}
+	return sessions
+}
+
+// avatarKeys — файлы загруженного аватара в хранилище.
+func avatarKeys(user models.User) []string {
+	if user.AvatarKey != "" {
+		keys := make([]string, len(utils.AvatarSizes))
+		for i, size := range utils.AvatarSizes {
+			keys[i] = user.AvatarKey + "/" + utils.AvatarFileName(size)
+		}
+		return keys
+	}
+	// старые аватары хранились одним файлом прямо в uploads
+	if key, ok := strings.CutPrefix(user.AvatarURL, "/uploads/"); ok {
+		return []string{key}
+	}
+	return nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, r io.Reader) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeZipFile(zw, name, strings.NewReader(string(data)))
+}
+
+// copyToZip копирует файл из хранилища. Пропавший файл пропускается: архив
+// без него полезнее, чем никакого.
+func copyToZip(ctx context.Context, zw *zip.Writer, name, key string) error {
+	r, err := filestore.Default.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			logging.FromContext(ctx).Warn("Файл для выгрузки данных не найден", "key", key)
+			return nil
+		}
+		return err
+	}
+	defer r.Close()
+	return writeZipFile(zw, name, r)
+}
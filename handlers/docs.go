@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"Blog/openapi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func OpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openapi.JSON())
+}
+
+func APIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/filestore"
+	"Blog/i18n"
+	"Blog/logging"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"time"
+)
+
+// EraseUser безвозвратно стирает личные данные пользователя. Строка в users
+// остаётся обезличенной: на её id ссылаются журнал аудита и файлы в
+// опубликованных постах, которые остаются под псевдонимом. Email, ник,
+// пароль и аватар стираются, файлы без опубликованных ссылок и архивы
+// выгрузок данных удаляются, незавершённые загрузки отменяются, из журнала
+// стираются личные данные (audit.RedactUser). record пишет запись о
+// стирании в той же транзакции.
+func EraseUser(ctx context.Context, user models.User, record func(tx *gorm.DB, metadata audit.Metadata) error) error {
+	var uploads []models.MediaUpload
+	if err := storage.DB.WithContext(ctx).Where("user_id = ?", user.ID).Find(&uploads).Error; err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		if err := DiscardUpload(ctx, upload); err != nil {
+			return err
+		}
+	}
+
+	var exports []models.DataExport
+	if err := storage.DB.WithContext(ctx).Where("user_id = ? AND key <> ''", user.ID).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	var media []models.Media
+	now := time.Now()
+	err := storage.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.User{}).Where("id = ? AND erased_at IS NULL", user.ID).Updates(map[string]interface{}{
+			"email":         fmt.Sprintf("erased-%d@erased.invalid", user.ID),
+			"nickname":      fmt.Sprintf("deleted-user-%d", user.ID),
+			"password":      "",
+			"avatar_url":    "",
+			"avatar_key":    "",
+			"language":      "",
+			"status":        models.StatusActive,
+			"status_reason": "",
+			"status_until":  nil,
+			"deleted_at":    gorm.Expr("COALESCE(deleted_at, ?)", now),
+			"erased_at":     now,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apierr.UserErased
+		}
+
+		// файлы блокируются, чтобы SetMediaReferences не опубликовал ссылку
+		// на уже выбранный к удалению
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND NOT EXISTS (SELECT 1 FROM media_references r WHERE r.media_id = media.id AND r.published)", user.ID).
+			Find(&media).Error; err != nil {
+			return err
+		}
+		mediaIDs := make([]uint, len(media))
+		for i, m := range media {
+			mediaIDs[i] = m.ID
+		}
+		if len(mediaIDs) > 0 {
+			if err := tx.Where("media_id IN ?", mediaIDs).Delete(&models.MediaReference{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", mediaIDs).Delete(&models.Media{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// архивы выгрузок — полная копия личных данных
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+
+		redacted, err := audit.RedactUser(ctx, tx, user.ID, user.Email)
+		if err != nil {
+			return err
+		}
+		return record(tx, audit.Details("redacted_entries", redacted, "deleted_media", len(media)))
+	})
+	if err != nil {
+		return err
+	}
+
+	// файлы удаляются после фиксации: если транзакция откатится, ссылки на них останутся
+	removeOldAvatar(ctx, user)
+	for _, m := range media {
+		deleteMediaFiles(ctx, m)
+	}
+	for _, export := range exports {
+		if err := filestore.Default.Delete(ctx, export.Key); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при удалении архива выгрузки", "key", export.Key, "error", err)
+		}
+	}
+	return nil
+}
+
+// PurgeUser стирает данные пользователя сразу, не дожидаясь окончания
+// users.erasure_grace. Ещё не удалённый аккаунт удаляется тем же действием.
+func PurgeUser(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierr.InvalidID)
+		return
+	}
+	if uint(targetID) == c.GetUint("user_id") {
+		c.Error(apierr.CannotModerateSelf)
+		return
+	}
+
+	var user models.User
+	if err := storage.DB.WithContext(c).Unscoped().First(&user, targetID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
+		return
+	}
+	if user.ErasedAt != nil {
+		c.Error(apierr.UserErased)
+		return
+	}
+
+	err = EraseUser(c.Request.Context(), user, func(tx *gorm.DB, metadata audit.Metadata) error {
+		return utils.LogAudit(c, tx, "purge_user", "user", user.ID, metadata)
+	})
+	if errors.Is(err, apierr.UserErased) {
+		c.Error(apierr.UserErased)
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"message": i18n.T(c, "messages.user_erased"),
+	})
+}
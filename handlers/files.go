@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/filestore"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// ServeSignedFile отдаёт приватный файл локального хранилища по ссылке из SignedURL.
+func ServeSignedFile(c *gin.Context) {
+	local, ok := filestore.LocalStore()
+	if !ok {
+		c.Error(apierr.FileNotFound)
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Request.URL.Query()); err != nil {
+		if errors.Is(err, filestore.ErrLinkExpired) {
+			c.Error(apierr.LinkExpired)
+			return
+		}
+		c.Error(apierr.InvalidSignature)
+		return
+	}
+
+	file, err := local.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) || errors.Is(err, filestore.ErrInvalidKey) {
+			c.Error(apierr.FileNotFound)
+			return
+		}
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", fileContentType(key))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, file)
+}
+
+// ServePublicFile отдаёт файл локального хранилища по прямой ссылке из URL.
+// Раздаются только публичные каталоги (filestore.IsPublic), остальные ключи
+// доступны лишь через ServeSignedFile.
+func ServePublicFile(c *gin.Context) {
+	local, ok := filestore.LocalStore()
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !ok || !filestore.IsPublic(key) {
+		c.Error(apierr.FileNotFound)
+		return
+	}
+
+	file, err := local.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) || errors.Is(err, filestore.ErrInvalidKey) {
+			c.Error(apierr.FileNotFound)
+			return
+		}
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	content, ok := file.(*os.File)
+	if !ok {
+		c.Error(apierr.Internal)
+		return
+	}
+	info, err := content.Stat()
+	if err != nil || info.IsDir() {
+		c.Error(apierr.FileNotFound)
+		return
+	}
+
+	contentType := fileContentType(key)
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if !inlineFileTypes[contentType] {
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	}
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime(), content)
+}
+
+// inlineFileTypes — типы, которые браузер показывает прямо на странице.
+// Остальные файлы пользователей отдаются на скачивание, чтобы загруженный
+// документ не открывался с домена приложения.
+var inlineFileTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+func fileContentType(key string) string {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		return "application/octet-stream"
+	}
+	return contentType
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"Blog/migrate"
+	"Blog/storage"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	draining          atomic.Bool
+	migrationsApplied atomic.Bool
+)
+
+// MarkDraining переводит /readyz в 503, чтобы балансировщик перестал слать
+// новые запросы, пока сервер завершает текущие.
+func MarkDraining() {
+	draining.Store(true)
+}
+
+// Healthz — liveness: процесс жив и обрабатывает запросы.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz — readiness: база доступна, все миграции применены, сервер не останавливается.
+func Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	if draining.Load() {
+		checks["server"] = "shutting down"
+		ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	if err := storage.Ping(ctx); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if ready && !migrationsApplied.Load() {
+		pending, err := migrate.New(storage.DB.WithContext(ctx)).Pending()
+		switch {
+		case err != nil:
+			checks["migrations"] = err.Error()
+			ready = false
+		case pending > 0:
+			checks["migrations"] = gin.H{"pending": pending}
+			ready = false
+		default:
+			migrationsApplied.Store(true)
+		}
+	}
+	if migrationsApplied.Load() {
+		checks["migrations"] = "ok"
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
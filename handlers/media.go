@@ -0,0 +1,732 @@
+package handlers
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/config"
+	"Blog/dto"
+	"Blog/filestore"
+	"Blog/i18n"
+	"Blog/logging"
+	"Blog/metrics"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxThumbnailSourceBytes = 20 << 20
+	mediaThumbnailSize      = 256
+	mediaSniffBytes         = 3072 // столько байт начала файла смотрит mimetype
+	MediaUploadTTL          = 24 * time.Hour
+)
+
+// Uploads — обработчики загрузки файлов: аватара и медиатеки. Ограничения
+// на размеры и квота задаются из конфигурации при создании.
+type Uploads struct {
+	limits config.Limits
+}
+
+func NewUploads(limits config.Limits) *Uploads {
+	return &Uploads{limits: limits}
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// mediaKinds — значения фильтра type в ListMedia.
+var mediaKinds = map[string]bool{"image": true, "video": true, "audio": true, "application": true, "text": true}
+
+// mediaTypes — типы, которые можно загружать в медиатеку, с расширением для
+// каждого. Тип определяется по содержимому, и имя файла получает расширение
+// этого типа: иначе HTML или SVG раздавались бы с домена приложения.
+var mediaTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"audio/mpeg":      ".mp3",
+	"audio/ogg":       ".ogg",
+	"audio/wav":       ".wav",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+func (u *Uploads) UploadMedia(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.Error(apierr.FileRequired)
+		return
+	}
+	if file.Size > u.limits.MaxMediaUploadBytes {
+		c.Error(apierr.MediaTooLarge)
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.Error(apierr.FileUnreadable)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, u.limits.MaxMediaUploadBytes+1))
+	src.Close()
+	if err != nil || int64(len(data)) > u.limits.MaxMediaUploadBytes {
+		c.Error(apierr.MediaTooLarge)
+		return
+	}
+
+	contentType, fileName, ok := mediaType(mimetype.Detect(data), sanitizeFileName(file.Filename))
+	if !ok {
+		c.Error(apierr.MediaTypeNotAllowed)
+		return
+	}
+
+	ctx := c.Request.Context()
+	media := models.Media{
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	prefix := mediaPrefix(userID)
+	media.Key = prefix + "/" + media.FileName
+
+	if err := filestore.Default.Put(ctx, media.Key, bytes.NewReader(data), media.Size, media.ContentType); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	attachThumbnail(ctx, &media, prefix, data)
+
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := u.reserveQuota(tx, userID, media.Size); err != nil {
+			return err
+		}
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "upload_media", "media", media.ID, audit.Details("file_name", media.FileName))
+	})
+	if err != nil {
+		deleteMediaFiles(ctx, media)
+		if errors.Is(err, apierr.QuotaExceeded) {
+			c.Error(apierr.QuotaExceeded)
+			return
+		}
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	metrics.Uploads.WithLabelValues("media").Inc()
+
+	utils.RespondCreated(c, gin.H{
+		"media": dto.ToMediaResponse(media),
+	})
+}
+
+func (u *Uploads) CreateMediaUpload(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var input dto.CreateUploadInput
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
+		return
+	}
+	// заявленный тип — только подсказка: при сборке файла он определяется заново
+	if contentType, _, _ := strings.Cut(input.ContentType, ";"); contentType != "" && mediaTypes[strings.TrimSpace(contentType)] == "" {
+		c.Error(apierr.MediaTypeNotAllowed)
+		return
+	}
+	if input.Size > u.limits.MaxMediaFileBytes {
+		c.Error(apierr.UploadTooLarge.WithDetail("max_bytes", u.limits.MaxMediaFileBytes))
+		return
+	}
+
+	upload := models.MediaUpload{
+		ID:          utils.RandomToken(16),
+		UserID:      userID,
+		FileName:    sanitizeFileName(input.FileName),
+		ContentType: input.ContentType,
+		Size:        input.Size,
+		ExpiresAt:   time.Now().Add(MediaUploadTTL),
+	}
+	err := storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := u.reserveQuota(tx, userID, upload.Size); err != nil {
+			return err
+		}
+		return tx.Create(&upload).Error
+	})
+	if errors.Is(err, apierr.QuotaExceeded) {
+		c.Error(apierr.QuotaExceeded)
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	utils.RespondCreated(c, gin.H{
+		"upload":     dto.ToMediaUploadResponse(upload),
+		"chunk_size": u.limits.MaxMediaChunkBytes,
+	})
+}
+
+func GetMediaUpload(c *gin.Context) {
+	upload, ok := findUpload(c)
+	if !ok {
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"upload": dto.ToMediaUploadResponse(upload),
+	})
+}
+
+// UploadMediaChunk принимает очередную часть файла. Смещение передаётся в
+// заголовке Upload-Offset и должно совпадать с уже полученным объёмом, поэтому
+// после обрыва клиент узнаёт received через GET и продолжает с него.
+func (u *Uploads) UploadMediaChunk(c *gin.Context) {
+	upload, ok := findUpload(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.Error(apierr.InvalidUploadOffset)
+		return
+	}
+	if offset != upload.Received {
+		c.Error(apierr.UploadOffsetMismatch.WithDetail("received", upload.Received))
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, u.limits.MaxMediaChunkBytes+1))
+	if err != nil {
+		c.Error(apierr.BodyUnreadable.Wrap(err))
+		return
+	}
+	size := int64(len(data))
+	if size == 0 {
+		c.Error(apierr.ChunkEmpty)
+		return
+	}
+	if size > u.limits.MaxMediaChunkBytes {
+		c.Error(apierr.ChunkTooLarge)
+		return
+	}
+	if offset+size > upload.Size {
+		c.Error(apierr.UploadSizeExceeded)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := uploadPartKey(upload.ID, offset)
+	if err := filestore.Default.Put(ctx, key, bytes.NewReader(data), size, "application/octet-stream"); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.MediaUpload{}).
+			Where("id = ? AND received = ?", upload.ID, offset).
+			Update("received", offset+size)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errUploadConflict
+		}
+		return tx.Create(&models.MediaUploadPart{UploadID: upload.ID, StartOffset: offset, Size: size}).Error
+	})
+	if err != nil {
+		filestore.Default.Delete(ctx, key)
+		if errors.Is(err, errUploadConflict) {
+			c.Error(apierr.UploadConflict)
+			return
+		}
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	upload.Received = offset + size
+	utils.RespondOK(c, gin.H{
+		"upload": dto.ToMediaUploadResponse(upload),
+	})
+}
+
+func CompleteMediaUpload(c *gin.Context) {
+	upload, ok := findUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Received != upload.Size {
+		c.Error(apierr.UploadIncomplete)
+		return
+	}
+
+	var parts []models.MediaUploadPart
+	if err := storage.DB.WithContext(c).Where("upload_id = ?", upload.ID).Order("start_offset asc").Find(&parts).Error; err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	if len(parts) == 0 {
+		c.Error(apierr.UploadIncomplete)
+		return
+	}
+
+	ctx := c.Request.Context()
+	mtype, err := detectUploadType(ctx, parts)
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	contentType, fileName, ok := mediaType(mtype, upload.FileName)
+	if !ok {
+		if err := DiscardUpload(ctx, upload); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при отмене загрузки", "upload_id", upload.ID, "error", err)
+		}
+		c.Error(apierr.MediaTypeNotAllowed)
+		return
+	}
+
+	prefix := mediaPrefix(upload.UserID)
+	media := models.Media{
+		UserID:      upload.UserID,
+		FileName:    fileName,
+		Key:         prefix + "/" + fileName,
+		ContentType: contentType,
+		Size:        upload.Size,
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(copyUploadParts(ctx, pw, parts))
+	}()
+	err = filestore.Default.Put(ctx, media.Key, pr, media.Size, media.ContentType)
+	pr.Close()
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	if strings.HasPrefix(media.ContentType, "image/") && media.Size <= maxThumbnailSourceBytes {
+		if data, err := readStored(ctx, media.Key); err == nil {
+			attachThumbnail(ctx, &media, prefix, data)
+		}
+	}
+
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// параллельный complete той же загрузки уже мог создать файл
+		res := tx.Delete(&upload)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apierr.UploadNotFound
+		}
+		if err := tx.Where("upload_id = ?", upload.ID).Delete(&models.MediaUploadPart{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "upload_media", "media", media.ID, audit.Details("file_name", media.FileName))
+	})
+	if err != nil {
+		deleteMediaFiles(ctx, media)
+		if errors.Is(err, apierr.UploadNotFound) {
+			c.Error(apierr.UploadNotFound)
+			return
+		}
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	deleteUploadParts(ctx, parts)
+
+	metrics.Uploads.WithLabelValues("media").Inc()
+
+	utils.RespondCreated(c, gin.H{
+		"media": dto.ToMediaResponse(media),
+	})
+}
+
+func AbortMediaUpload(c *gin.Context) {
+	upload, ok := findUpload(c)
+	if !ok {
+		return
+	}
+
+	if err := DiscardUpload(c.Request.Context(), upload); err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	utils.RespondOK(c, gin.H{
+		"message": i18n.T(c, "messages.upload_aborted"),
+	})
+}
+
+// DiscardUpload удаляет незавершённую загрузку вместе с уже полученными частями.
+func DiscardUpload(ctx context.Context, upload models.MediaUpload) error {
+	var parts []models.MediaUploadPart
+	if err := storage.DB.WithContext(ctx).Where("upload_id = ?", upload.ID).Find(&parts).Error; err != nil {
+		return err
+	}
+
+	err := storage.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", upload.ID).Delete(&models.MediaUploadPart{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&upload).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	deleteUploadParts(ctx, parts)
+	return nil
+}
+
+func (u *Uploads) ListMedia(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := storage.DB.WithContext(c).Model(&models.Media{}).Where("user_id = ?", userID)
+	if search := c.Query("search"); search != "" {
+		query = query.Where(`file_name ILIKE ? ESCAPE '\'`, utils.ContainsPattern(search))
+	}
+	if kind := c.Query("type"); kind != "" {
+		if !mediaKinds[kind] {
+			c.Error(apierr.InvalidQuery.WithDetail("param", "type"))
+			return
+		}
+		query = query.Where("content_type LIKE ?", kind+"/%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var media []models.Media
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&media).Error; err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+
+	var used int64
+	storage.DB.WithContext(c).Model(&models.Media{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&used)
+
+	utils.RespondOK(c, gin.H{
+		"media": dto.ToMediaList(media),
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"quota": gin.H{
+			"used":  used,
+			"limit": u.limits.MediaQuotaBytes,
+		},
+	})
+}
+
+func DeleteMedia(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	mediaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierr.InvalidID)
+		return
+	}
+
+	var media models.Media
+	if err := storage.DB.WithContext(c).Where("id = ? AND user_id = ?", mediaID, userID).First(&media).Error; err != nil {
+		c.Error(apierr.MediaNotFound)
+		return
+	}
+
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// блокировка файла не даёт SetMediaReferences опубликовать ссылку,
+		// пока проверяем и удаляем
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Media{}, media.ID).Error; err != nil {
+			return apierr.MediaNotFound
+		}
+		var published int64
+		if err := tx.Model(&models.MediaReference{}).Where("media_id = ? AND published", media.ID).Count(&published).Error; err != nil {
+			return err
+		}
+		if published > 0 {
+			return apierr.MediaInUse
+		}
+
+		if err := tx.Where("media_id = ?", media.ID).Delete(&models.MediaReference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&media).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "delete_media", "media", media.ID, audit.Details("file_name", media.FileName))
+	})
+	if errors.Is(err, apierr.MediaInUse) || errors.Is(err, apierr.MediaNotFound) {
+		c.Error(err)
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
+		return
+	}
+	deleteMediaFiles(c.Request.Context(), media)
+
+	utils.RespondOK(c, gin.H{
+		"message": i18n.T(c, "messages.media_deleted"),
+	})
+}
+
+// SetMediaReferences записывает, какие файлы пользователя userID использует
+// объект (object, objectID), заменяя прежний список, и отмечает ссылки
+// опубликованными или нет. Вызывается в транзакции сохранения самого
+// объекта (например, поста). Пока на файл есть опубликованная ссылка,
+// DeleteMedia отвечает media_in_use, а EraseUser оставляет файл. Чужой или
+// удалённый файл в mediaIDs — apierr.MediaNotFound.
+func SetMediaReferences(tx *gorm.DB, userID uint, object string, objectID uint, mediaIDs []uint, published bool) error {
+	ids := uniqueIDs(mediaIDs)
+	if len(ids) > 0 {
+		// FOR SHARE: параллельный DeleteMedia дождётся конца транзакции и увидит ссылки
+		var found []uint
+		err := tx.Model(&models.Media{}).Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("id IN ? AND user_id = ?", ids, userID).Pluck("id", &found).Error
+		if err != nil {
+			return err
+		}
+		if len(found) != len(ids) {
+			return apierr.MediaNotFound
+		}
+	}
+
+	if err := tx.Where("object = ? AND object_id = ?", object, objectID).Delete(&models.MediaReference{}).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	refs := make([]models.MediaReference, len(ids))
+	for i, id := range ids {
+		refs[i] = models.MediaReference{MediaID: id, Object: object, ObjectID: objectID, Published: published}
+	}
+	return tx.Create(&refs).Error
+}
+
+// PublishMediaReferences отмечает ссылки объекта опубликованными или снимает
+// отметку, например при публикации поста или возврате его в черновики.
+func PublishMediaReferences(tx *gorm.DB, object string, objectID uint, published bool) error {
+	refs := tx.Model(&models.MediaReference{}).Select("media_id").Where("object = ? AND object_id = ?", object, objectID)
+	// как в SetMediaReferences: удаление файла ждёт конца транзакции
+	var locked []uint
+	if err := tx.Model(&models.Media{}).Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id IN (?)", refs).Pluck("id", &locked).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.MediaReference{}).Where("object = ? AND object_id = ?", object, objectID).
+		Update("published", published).Error
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+var errUploadConflict = errors.New("upload offset conflict")
+
+func findUpload(c *gin.Context) (models.MediaUpload, bool) {
+	var upload models.MediaUpload
+	err := storage.DB.WithContext(c).Where("id = ? AND user_id = ? AND expires_at > ?", c.Param("id"), c.GetUint("user_id"), time.Now()).
+		First(&upload).Error
+	if err != nil {
+		c.Error(apierr.UploadNotFound)
+		return upload, false
+	}
+	return upload, true
+}
+
+// reserveQuota проверяет, что size помещается в квоту вместе с готовыми
+// файлами и объявленным размером незавершённых загрузок, и возвращает
+// apierr.QuotaExceeded, если нет. Вызывается в транзакции, которая затем
+// создаёт файл или загрузку: строка пользователя заблокирована до её конца,
+// поэтому параллельные загрузки проверяют квоту по очереди.
+func (u *Uploads) reserveQuota(tx *gorm.DB, userID uint, size int64) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().Select("id").First(&models.User{}, userID).Error; err != nil {
+		return err
+	}
+
+	var used, pending int64
+	if err := tx.Model(&models.Media{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.MediaUpload{}).Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Select("COALESCE(SUM(size), 0)").Scan(&pending).Error; err != nil {
+		return err
+	}
+	if used+pending+size > u.limits.MediaQuotaBytes {
+		return apierr.QuotaExceeded
+	}
+	return nil
+}
+
+func mediaPrefix(userID uint) string {
+	return fmt.Sprintf("media/%d/%s", userID, utils.RandomToken(8))
+}
+
+func uploadPartKey(uploadID string, offset int64) string {
+	return fmt.Sprintf("tmp/uploads/%s/%020d", uploadID, offset)
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		name = "file"
+	}
+	if len(name) > 100 {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:100-len(ext)], "") + ext
+	}
+	return name
+}
+
+// mediaType проверяет тип, определённый по содержимому, по mediaTypes и
+// меняет расширение fileName на расширение этого типа.
+func mediaType(mtype *mimetype.MIME, fileName string) (contentType, name string, ok bool) {
+	contentType, _, _ = strings.Cut(mtype.String(), ";")
+	ext, ok := mediaTypes[contentType]
+	if !ok {
+		return "", "", false
+	}
+	return contentType, strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ext, true
+}
+
+func attachThumbnail(ctx context.Context, media *models.Media, prefix string, data []byte) {
+	img, err := utils.DecodeImage(data)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := utils.EncodePNG(&buf, utils.SquareThumbnail(img, mediaThumbnailSize)); err != nil {
+		return
+	}
+	key := prefix + "/thumb.png"
+	if err := filestore.Default.Put(ctx, key, &buf, int64(buf.Len()), utils.AvatarContentType); err != nil {
+		logging.FromContext(ctx).Error("Ошибка при сохранении миниатюры", "key", key, "error", err)
+		return
+	}
+
+	media.ThumbnailKey = key
+	media.Width = img.Bounds().Dx()
+	media.Height = img.Bounds().Dy()
+}
+
+func deleteMediaFiles(ctx context.Context, media models.Media) {
+	for _, key := range []string{media.Key, media.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := filestore.Default.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при удалении файла", "key", key, "error", err)
+		}
+	}
+}
+
+// detectUploadType определяет тип по началу собранного файла: первая часть
+// может оказаться короче, чем нужно для распознавания.
+func detectUploadType(ctx context.Context, parts []models.MediaUploadPart) (*mimetype.MIME, error) {
+	var head []byte
+	for _, part := range parts {
+		if len(head) >= mediaSniffBytes {
+			break
+		}
+		r, err := filestore.Default.Get(ctx, uploadPartKey(part.UploadID, part.StartOffset))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, min(part.Size, int64(mediaSniffBytes-len(head))))
+		_, err = io.ReadFull(r, buf)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("часть файла со смещением %d: %w", part.StartOffset, err)
+		}
+		head = append(head, buf...)
+	}
+	return mimetype.Detect(head), nil
+}
+
+func copyUploadParts(ctx context.Context, w io.Writer, parts []models.MediaUploadPart) error {
+	var expected int64
+	for _, part := range parts {
+		if part.StartOffset != expected {
+			return fmt.Errorf("пропущена часть файла со смещением %d", expected)
+		}
+		r, err := filestore.Default.Get(ctx, uploadPartKey(part.UploadID, part.StartOffset))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		expected += part.Size
+	}
+	return nil
+}
+
+func deleteUploadParts(ctx context.Context, parts []models.MediaUploadPart) {
+	for _, part := range parts {
+		if err := filestore.Default.Delete(ctx, uploadPartKey(part.UploadID, part.StartOffset)); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при удалении части загрузки", "upload_id", part.UploadID, "error", err)
+		}
+	}
+}
+
+func readStored(ctx context.Context, key string) ([]byte, error) {
+	r, err := filestore.Default.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"Blog/config"
+	"Blog/filestore"
+	"Blog/models"
+	"bytes"
+	"context"
+	"github.com/gabriel-vasile/mimetype"
+	"testing"
+)
+
+func TestMediaType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name     string
+		data     []byte
+		fileName string
+		want     string
+		ok       bool
+	}{
+		{"картинка с чужим расширением", png, "photo.html", "photo.png", true},
+		{"текст", []byte("hello"), "notes", "notes.txt", true},
+		{"html", []byte("<!DOCTYPE html><script>alert(1)</script>"), "page.txt", "", false},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), "a.svg", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, name, ok := mediaType(mimetype.Detect(tt.data), tt.fileName)
+			if ok != tt.ok || name != tt.want {
+				t.Errorf("получили (%q, %v), want (%q, %v)", name, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDetectUploadTypeAcrossParts(t *testing.T) {
+	filestore.Init(config.Storage{Driver: "local", LocalDir: t.TempDir(), SigningKey: "0123456789abcdef"})
+	ctx := context.Background()
+
+	// первая часть — один байт PNG-сигнатуры: по ней одной тип не определить
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	parts := []models.MediaUploadPart{
+		{UploadID: "test", StartOffset: 0, Size: 1},
+		{UploadID: "test", StartOffset: 1, Size: int64(len(data) - 1)},
+	}
+	for _, part := range parts {
+		chunk := data[part.StartOffset : part.StartOffset+part.Size]
+		if err := filestore.Default.Put(ctx, uploadPartKey(part.UploadID, part.StartOffset), bytes.NewReader(chunk), part.Size, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mtype, err := detectUploadType(ctx, parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mtype.Is("image/png") {
+		t.Errorf("тип %s, want image/png", mtype)
+	}
+
+	parts[1].Size++
+	if _, err := detectUploadType(ctx, parts); err == nil {
+		t.Error("часть короче записанного размера должна давать ошибку")
+	}
+}
@@ -1,33 +1,54 @@
 package handlers
 
 import (
+	"Blog/apierr"
+	"Blog/audit"
 	"Blog/dto"
+	"Blog/filestore"
+	"Blog/i18n"
+	"Blog/logging"
+	"Blog/metrics"
 	"Blog/models"
 	"Blog/storage"
 	"Blog/utils"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
-	"net/http"
-	"os"
-	"path/filepath"
+	"gorm.io/gorm"
+	"image"
+	"io"
 	"strconv"
 	"strings"
+	"time"
 )
 
-var validate = validator.New()
+var validate = i18n.Validator()
+
+// bindJSON разбирает тело запроса и проверяет его validate-тегами.
+func bindJSON(c *gin.Context, input interface{}) error {
+	if err := c.ShouldBindJSON(input); err != nil {
+		return apierr.InvalidJSON.Wrap(err)
+	}
+	if err := validate.Struct(input); err != nil {
+		// сообщения по полям переводит middleware.Errors на язык запроса
+		return apierr.ValidationFailed.Wrap(err)
+	}
+	return nil
+}
 
 func GetCurrentUser(c *gin.Context) {
 	userID, ok := c.Get("user_id")
 	if !ok {
-		utils.RespondError(c, http.StatusUnauthorized, "Пользователь не найден")
+		c.Error(apierr.AccountNotFound)
 		return
 	}
 
 	var user models.User
 
-	if err := storage.DB.First(&user, userID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	if err := storage.DB.WithContext(c).First(&user, userID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
 		return
 	}
 
@@ -40,21 +61,20 @@ func CreateUser(c *gin.Context) {
 
 	var input dto.CreateUserInput
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		errors := utils.FormatValidationError(err)
-		utils.RespondError(c, http.StatusBadRequest, errors)
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var existing models.User
-	if err := storage.DB.Where("email = ?", input.Email).First(&existing).Error; err == nil {
-		utils.RespondError(c, http.StatusBadRequest, "Email уже зарегистрирован")
+	if err := storage.DB.WithContext(c).Where("email = ?", input.Email).First(&existing).Error; err == nil {
+		c.Error(apierr.EmailTaken)
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(input.Password)
+	hashedPassword, err := utils.HashPassword(c, input.Password)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось зашифровать пароль")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -64,8 +84,14 @@ func CreateUser(c *gin.Context) {
 		Password: hashedPassword,
 	}
 
-	if err := storage.DB.Create(&user).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка при создании пользователя")
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "create_user", "user", user.ID, audit.Metadata{})
+	})
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -80,40 +106,54 @@ func UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	targetID, err := strconv.Atoi(idParam)
 	if err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Некорректный ID")
+		c.Error(apierr.InvalidID)
 		return
 	}
 
 	var user models.User
-	if err := storage.DB.First(&user, targetID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	if err := storage.DB.WithContext(c).First(&user, targetID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
 		return
 	}
 
 	var input dto.UpdateUserInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		errors := utils.FormatValidationError(err)
-		utils.RespondError(c, http.StatusBadRequest, errors)
+	if err := bindJSON(c, &input); err != nil {
+		c.Error(err)
 		return
 	}
 
+	before := user
 	if input.Nickname != "" {
 		user.Nickname = input.Nickname
 	}
 	if input.Email != "" {
 		user.Email = input.Email
 	}
+	if input.Language != nil {
+		if *input.Language != "" && !i18n.IsSupported(*input.Language) {
+			c.Error(apierr.UnsupportedLanguage)
+			return
+		}
+		user.Language = *input.Language
+	}
 	if input.Password != "" {
-		hashedPassword, err := utils.HashPassword(input.Password)
+		hashedPassword, err := utils.HashPassword(c, input.Password)
 		if err != nil {
-			utils.RespondError(c, http.StatusInternalServerError, "Не удалось зашифровать пароль")
+			c.Error(apierr.Internal.Wrap(err))
 			return
 		}
 		user.Password = hashedPassword
 	}
 
-	if err := storage.DB.Save(&user).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка при обновлении пользователя")
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// только поля профиля: роль и блокировку меняет модерация
+		if err := tx.Model(&user).Select("nickname", "email", "language", "password").Updates(&user).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "update_user", "user", user.ID, audit.Metadata{Changes: audit.Diff(before, user)})
+	})
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -121,11 +161,6 @@ func UpdateUser(c *gin.Context) {
 		"user": dto.ToUserResponse(user),
 	})
 
-	inputForLog := input
-	inputForLog.Password = ""
-	metadata := fmt.Sprintf("input: %+v", inputForLog)
-	utils.LogAudit(c, "update_user", "user", user.ID, metadata)
-
 }
 
 func DeleteUser(c *gin.Context) {
@@ -133,27 +168,31 @@ func DeleteUser(c *gin.Context) {
 	idParam := c.Param("id")
 	targetID, err := strconv.Atoi(idParam)
 	if err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Некорректный ID")
+		c.Error(apierr.InvalidID)
 		return
 	}
 
 	var user models.User
-	if err := storage.DB.First(&user, targetID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	if err := storage.DB.WithContext(c).First(&user, targetID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
 		return
 	}
 
-	if err := storage.DB.Delete(&user).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка при удалении")
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "delete_user", "user", user.ID, audit.Metadata{})
+	})
+	if err != nil {
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
 	utils.RespondOK(c, gin.H{
-		"message": "Пользователь удален",
+		"message": i18n.T(c, "messages.user_deleted"),
 	})
 
-	utils.LogAudit(c, "delete_user", "user", user.ID, "")
-
 }
 
 func GetUsers(c *gin.Context) {
@@ -189,20 +228,20 @@ func GetUsers(c *gin.Context) {
 		sortDirection = "DESC"
 	}
 
-	query := storage.DB.Model(&models.User{})
+	query := storage.DB.WithContext(c).Model(&models.User{})
 	if search := c.Query("search"); search != "" {
-		term := "%" + search + "%"
-		query = query.Where("nickname ILIKE ? OR email ILIKE ?", term, term)
+		term := utils.ContainsPattern(search)
+		query = query.Where(`nickname ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\'`, term, term)
 	}
 
 	if role := c.Query("role"); role != "" {
 		query = query.Where("role = ?", role)
 	}
 	if email := c.Query("email"); email != "" {
-		query = query.Where("email ILIKE ?", "%"+email+"%")
+		query = query.Where(`email ILIKE ? ESCAPE '\'`, utils.ContainsPattern(email))
 	}
 	if nickname := c.Query("nickname"); nickname != "" {
-		query = query.Where("nickname ILIKE ?", "%"+nickname+"%")
+		query = query.Where(`nickname ILIKE ? ESCAPE '\'`, utils.ContainsPattern(nickname))
 	}
 
 	var total int64
@@ -211,7 +250,7 @@ func GetUsers(c *gin.Context) {
 	query = query.Order(fmt.Sprintf("%s %s", orderBy, sortDirection)).Limit(limit).Offset(offset)
 
 	if err := query.Find(&users).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Ошибка при получении пользователей")
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
@@ -233,13 +272,13 @@ func GetUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Некорректный ID")
+		c.Error(apierr.InvalidID)
 		return
 	}
 
 	var user models.User
-	if err := storage.DB.First(&user, userID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	if err := storage.DB.WithContext(c).First(&user, userID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
 		return
 	}
 
@@ -248,46 +287,117 @@ func GetUser(c *gin.Context) {
 	})
 }
 
-func UploadAvatar(c *gin.Context) {
+func (u *Uploads) UploadAvatar(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	file, err := c.FormFile("avatar")
 	if err != nil {
-		utils.RespondError(c, http.StatusBadRequest, "Файл не найден")
+		c.Error(apierr.FileRequired)
+		return
+	}
+
+	if file.Size > u.limits.MaxAvatarBytes {
+		c.Error(apierr.FileTooLarge)
 		return
 	}
 
 	var user models.User
-	if err := storage.DB.First(&user, userID).Error; err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Пользователь не найден")
+	if err := storage.DB.WithContext(c).First(&user, userID).Error; err != nil {
+		c.Error(apierr.UserNotFound)
 		return
 	}
 
-	if user.AvatarURL != "" {
-		oldPath := strings.TrimPrefix(user.AvatarURL, "/")
-		if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
-			fmt.Println("Ошибка при удалении старого аватара:", err)
+	src, err := file.Open()
+	if err != nil {
+		c.Error(apierr.FileUnreadable)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, u.limits.MaxAvatarBytes+1))
+	src.Close()
+	if err != nil || int64(len(data)) > u.limits.MaxAvatarBytes {
+		c.Error(apierr.FileTooLarge)
+		return
+	}
+
+	img, err := utils.DecodeImage(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrImageTooLarge):
+			c.Error(apierr.ImageTooLarge)
+		case errors.Is(err, utils.ErrNotAnImage):
+			c.Error(apierr.NotAnImage)
+		default:
+			c.Error(apierr.ImageCorrupted)
 		}
+		return
 	}
 
-	filename := fmt.Sprintf("avatar_%d%s", userID, filepath.Ext(file.Filename))
-	path := filepath.Join("uploads", filename)
+	ctx := c.Request.Context()
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	prefix := fmt.Sprintf("avatars/%d/%s", userID, version)
 
-	if err := c.SaveUploadedFile(file, path); err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось сохранить файл")
-		return
+	for _, size := range utils.AvatarSizes {
+		if err := saveAvatarVariant(ctx, prefix, img, size); err != nil {
+			deleteAvatarFiles(ctx, prefix)
+			c.Error(apierr.Internal.Wrap(err))
+			return
+		}
 	}
 
-	avatarURL := "/uploads/" + filename
-	if err := storage.DB.Model(&models.User{}).Where("id = ?", userID).Update("avatar_url", avatarURL).Error; err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Не удалось обновить профиль")
+	largest := utils.AvatarSizes[len(utils.AvatarSizes)-1]
+	avatarURL := filestore.Default.URL(prefix + "/" + utils.AvatarFileName(largest))
+	err = storage.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"avatar_url": avatarURL,
+			"avatar_key": prefix,
+		}).Error; err != nil {
+			return err
+		}
+		return utils.LogAudit(c, tx, "upload_avatar", "user", user.ID, audit.Details("file_name", file.Filename))
+	})
+	if err != nil {
+		deleteAvatarFiles(ctx, prefix)
+		c.Error(apierr.Internal.Wrap(err))
 		return
 	}
 
+	removeOldAvatar(ctx, user)
+	metrics.Uploads.WithLabelValues("avatar").Inc()
+
 	utils.RespondOK(c, gin.H{
 		"avatar_url": avatarURL,
+		"avatars":    dto.AvatarURLs(avatarURL),
 	})
 
-	utils.LogAudit(c, "upload_avatar", "user", user.ID, file.Filename)
+}
+
+func saveAvatarVariant(ctx context.Context, prefix string, img image.Image, size int) error {
+	var buf bytes.Buffer
+	if err := utils.EncodePNG(&buf, utils.SquareThumbnail(img, size)); err != nil {
+		return err
+	}
+	key := prefix + "/" + utils.AvatarFileName(size)
+	return filestore.Default.Put(ctx, key, &buf, int64(buf.Len()), utils.AvatarContentType)
+}
+
+func deleteAvatarFiles(ctx context.Context, prefix string) {
+	for _, size := range utils.AvatarSizes {
+		if err := filestore.Default.Delete(ctx, prefix+"/"+utils.AvatarFileName(size)); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при удалении аватара", "key", prefix, "error", err)
+		}
+	}
+}
+
+func removeOldAvatar(ctx context.Context, user models.User) {
+	if user.AvatarKey != "" {
+		deleteAvatarFiles(ctx, user.AvatarKey)
+		return
+	}
 
+	// старые аватары хранились одним файлом прямо в uploads
+	if key, ok := strings.CutPrefix(user.AvatarURL, "/uploads/"); ok {
+		if err := filestore.Default.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("Ошибка при удалении старого аватара", "key", key, "error", err)
+		}
+	}
 }
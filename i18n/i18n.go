@@ -0,0 +1,112 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/language"
+	"path"
+	"strings"
+)
+
+// Языки API. Default используется, когда клиент ничего не выбрал.
+const (
+	Russian = "ru"
+	English = "en"
+	Kazakh  = "kk"
+	Default = Russian
+)
+
+// ContextKey — ключ, под которым middleware.Locale кладёт язык в gin.Context.
+const ContextKey = "lang"
+
+var Supported = []string{Russian, English, Kazakh}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogs = map[string]map[string]string{}
+	matcher  = language.NewMatcher([]language.Tag{language.Russian, language.English, language.Kazakh})
+)
+
+func init() {
+	for _, lang := range Supported {
+		data, err := localeFiles.ReadFile(path.Join("locales", lang+".json"))
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("locales/%s.json: %v", lang, err))
+		}
+		catalogs[lang] = messages
+	}
+}
+
+// IsSupported сообщает, есть ли каталог для языка.
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language.
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return Supported[index]
+}
+
+// FromContext возвращает язык запроса; работает и с *gin.Context.
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(ContextKey).(string); ok && IsSupported(lang) {
+		return lang
+	}
+	return Default
+}
+
+// T переводит ключ на язык запроса.
+func T(ctx context.Context, key string, args ...any) string {
+	return Translate(FromContext(ctx), key, args...)
+}
+
+// Translate ищет ключ в каталоге языка, затем в русском. Если ключа нет
+// нигде, возвращает сам ключ, чтобы пропуск был заметен.
+func Translate(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup как Translate, но сообщает, найден ли ключ.
+func Lookup(lang, key string) (string, bool) {
+	msg := Translate(lang, key)
+	return msg, msg != key
+}
+
+// Normalize приводит "en-US", "KK" и т.п. к поддерживаемому коду или "".
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	if IsSupported(lang) {
+		return lang
+	}
+	return ""
+}
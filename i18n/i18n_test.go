@@ -0,0 +1,61 @@
+package i18n
+
+import "testing"
+
+func TestTranslateFallback(t *testing.T) {
+	catalogs[Default]["test.only_russian"] = "Только %s"
+	catalogs[English]["test.translated"] = "Translated"
+	catalogs[Default]["test.translated"] = "Переведено"
+	t.Cleanup(func() {
+		delete(catalogs[Default], "test.only_russian")
+		delete(catalogs[English], "test.translated")
+		delete(catalogs[Default], "test.translated")
+	})
+
+	tests := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{English, "test.translated", nil, "Translated"},
+		{Kazakh, "test.translated", nil, "Переведено"},           // нет в kk — русский
+		{English, "test.only_russian", []any{"ru"}, "Только ru"}, // аргументы и в запасном
+		{"fr", "test.translated", nil, "Переведено"},             // неизвестный язык
+		{English, "test.missing", nil, "test.missing"},           // нигде нет — сам ключ
+	}
+	for _, tt := range tests {
+		if got := Translate(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+
+	if _, ok := Lookup(English, "test.missing"); ok {
+		t.Error("Lookup нашёл отсутствующий ключ")
+	}
+}
+
+func TestLocalesComplete(t *testing.T) {
+	for key := range catalogs[Default] {
+		for _, lang := range Supported {
+			if _, ok := catalogs[lang][key]; !ok {
+				t.Errorf("%s: нет перевода %s", lang, key)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                   Default,
+		"en-US,en;q=0.9":     English,
+		"kk-KZ":              Kazakh,
+		"fr-FR, en;q=0.5":    English,
+		"de":                 Default,
+		"ru;q=0.1, kk;q=0.9": Kazakh,
+	}
+	for header, want := range tests {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
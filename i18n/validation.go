@@ -0,0 +1,137 @@
+package i18n
+
+import (
+	"errors"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/kk"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	ru_translations "github.com/go-playground/validator/v10/translations/ru"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	universal     = ut.New(ru.New(), ru.New(), en.New(), kk.New())
+	validatorOnce sync.Once
+	shared        *validator.Validate
+)
+
+// Validator возвращает общий валидатор с переводами сообщений на все языки
+// API. Переводы регистрируются в общих переводчиках, поэтому экземпляр один.
+// В сообщениях и ключах ошибок используются имена полей из json-тегов.
+func Validator() *validator.Validate {
+	validatorOnce.Do(func() { shared = newValidator() })
+	return shared
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	ruTrans, _ := universal.GetTranslator(Russian)
+	enTrans, _ := universal.GetTranslator(English)
+	kkTrans, _ := universal.GetTranslator(Kazakh)
+	if err := ru_translations.RegisterDefaultTranslations(v, ruTrans); err != nil {
+		panic(err)
+	}
+	if err := en_translations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic(err)
+	}
+	if err := registerKazakh(v, kkTrans); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValidationErrors переводит ошибки валидатора в карту "поле → сообщение".
+func ValidationErrors(lang string, err error) map[string]string {
+	fields := map[string]string{}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return fields
+	}
+
+	trans, _ := universal.GetTranslator(lang)
+	for _, fe := range verrs {
+		msg := fe.Translate(trans)
+		// для тегов без перевода Translate возвращает технический текст
+		if msg == fe.Error() {
+			msg = Translate(lang, "validation.failed", fe.Field(), fe.Tag())
+		}
+		fields[fe.Field()] = msg
+	}
+	return fields
+}
+
+// В validator нет казахских переводов, поэтому они описаны здесь для тегов,
+// которые используются в dto. Остальные теги получают общее сообщение.
+var kazakhMessages = []struct {
+	tag, key, text string
+}{
+	{"required", "required", "{0} міндетті өріс"},
+	{"email", "email", "{0} дұрыс email болуы керек"},
+	{"url", "url", "{0} дұрыс URL болуы керек"},
+	{"oneof", "oneof", "{0} мына мәндердің бірі болуы керек: {1}"},
+	{"len", "len-string", "{0} ұзындығы {1} таңба болуы керек"},
+	{"len", "len-number", "{0} {1} болуы керек"},
+	{"min", "min-string", "{0} ұзындығы кемінде {1} таңба болуы керек"},
+	{"min", "min-number", "{0} кемінде {1} болуы керек"},
+	{"max", "max-string", "{0} ұзындығы {1} таңбадан аспауы керек"},
+	{"max", "max-number", "{0} {1} мәнінен аспауы керек"},
+	{"gt", "gt-number", "{0} {1} мәнінен үлкен болуы керек"},
+	{"gt", "gt-datetime", "{0} қазіргі уақыттан кейін болуы керек"},
+	{"gte", "gte-number", "{0} кемінде {1} болуы керек"},
+	{"lt", "lt-number", "{0} {1} мәнінен кіші болуы керек"},
+	{"lte", "lte-number", "{0} {1} мәнінен аспауы керек"},
+}
+
+func registerKazakh(v *validator.Validate, trans ut.Translator) error {
+	for _, m := range kazakhMessages {
+		if err := trans.Add(m.key, m.text, false); err != nil {
+			return err
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, m := range kazakhMessages {
+		if registered[m.tag] {
+			continue
+		}
+		registered[m.tag] = true
+
+		tag := m.tag
+		err := v.RegisterTranslation(tag, trans, func(ut.Translator) error { return nil }, func(t ut.Translator, fe validator.FieldError) string {
+			// для строк min/max/len говорят о длине, для чисел — о значении,
+			// для времени gt без параметра — о текущем моменте
+			key := tag + "-number"
+			switch fe.Kind() {
+			case reflect.String:
+				key = tag + "-string"
+			case reflect.Struct:
+				key = tag + "-datetime"
+			}
+			msg, err := t.T(key, fe.Field(), fe.Param())
+			if err != nil {
+				msg, err = t.T(tag, fe.Field(), fe.Param())
+			}
+			if err != nil {
+				return fe.Error()
+			}
+			return msg
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"Blog/audit"
+	"Blog/config"
+	"Blog/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// AuditRetention заранее создаёт месячные секции журнала аудита и
+// архивирует записи старше cfg.Retention.
+func AuditRetention(ctx context.Context, cfg config.Audit, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := audit.EnsurePartitions(ctx, storage.DB); err != nil {
+			slog.Error("Ошибка при создании секций журнала аудита", "error", err)
+		}
+		if cfg.Retention.Duration > 0 {
+			if err := audit.EnforceRetention(ctx, storage.DB, cfg.ArchiveDir, cfg.Retention.Duration); err != nil {
+				slog.Error("Ошибка при архивировании журнала аудита", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"Blog/filestore"
+	"Blog/handlers"
+	"Blog/models"
+	"Blog/storage"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+// staleExportTimeout — выгрузка в работе дольше этого считается брошенной
+// (экземпляр упал посреди сборки) и встаёт в очередь заново.
+const staleExportTimeout = time.Hour
+
+// BuildDataExports собирает запрошенные выгрузки данных по одной и удаляет
+// архивы, срок хранения которых истёк. Между проходами ждёт interval или
+// нового запроса. sessionTTL — срок действия refresh токена, см.
+// handlers.BuildDataExport.
+func BuildDataExports(ctx context.Context, ttl, sessionTTL, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := requeueStaleExports(ctx); err != nil {
+			slog.Error("Ошибка при возврате зависших выгрузок в очередь", "error", err)
+		}
+		for ctx.Err() == nil {
+			export, ok, err := claimDataExport(ctx)
+			if err != nil {
+				slog.Error("Ошибка при выборе выгрузки данных", "error", err)
+				break
+			}
+			if !ok {
+				break
+			}
+			if err := handlers.BuildDataExport(ctx, export, ttl, sessionTTL); err != nil {
+				slog.Error("Ошибка при сборке выгрузки данных", "export_id", export.ID, "user_id", export.UserID, "error", err)
+				storage.DB.WithContext(ctx).Model(&export).Updates(map[string]interface{}{
+					"status": models.ExportFailed,
+					"error":  err.Error(),
+				})
+			}
+		}
+		if err := removeExpiredExports(ctx); err != nil {
+			slog.Error("Ошибка при удалении истёкших выгрузок", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-handlers.ExportRequests():
+		}
+	}
+}
+
+// claimDataExport берёт в работу самую старую выгрузку из очереди. SKIP
+// LOCKED не даёт двум экземплярам взять одну и ту же.
+func claimDataExport(ctx context.Context) (models.DataExport, bool, error) {
+	var export models.DataExport
+	found := false
+	err := storage.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.ExportPending).Order("id").Limit(1).Find(&export)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		found = true
+		now := time.Now()
+		export.Status, export.StartedAt = models.ExportRunning, &now
+		return tx.Model(&export).Updates(map[string]interface{}{"status": export.Status, "started_at": now}).Error
+	})
+	return export, found, err
+}
+
+func requeueStaleExports(ctx context.Context) error {
+	return storage.DB.WithContext(ctx).Model(&models.DataExport{}).
+		Where("status = ? AND started_at < ?", models.ExportRunning, time.Now().Add(-staleExportTimeout)).
+		Update("status", models.ExportPending).Error
+}
+
+// removeExpiredExports удаляет архивы с истёкшим сроком; запись остаётся
+// со статусом expired, чтобы пользователь видел, что было с выгрузкой.
+func removeExpiredExports(ctx context.Context) error {
+	var exports []models.DataExport
+	if err := storage.DB.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", models.ExportReady, time.Now()).
+		Limit(100).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := filestore.Default.Delete(ctx, export.Key); err != nil {
+			slog.Error("Ошибка при удалении архива выгрузки", "export_id", export.ID, "key", export.Key, "error", err)
+			continue
+		}
+		if err := storage.DB.WithContext(ctx).Model(&export).
+			Updates(map[string]interface{}{"status": models.ExportExpired, "key": ""}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/handlers"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+// EraseDeletedUsers безвозвратно стирает личные данные пользователей,
+// удалённых больше grace назад. До этого удаление можно отменить через
+// PUT /admin/user/:id/restore.
+func EraseDeletedUsers(ctx context.Context, grace, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := eraseDeletedUsers(ctx, grace); err != nil {
+			slog.Error("Ошибка при поиске пользователей для стирания", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func eraseDeletedUsers(ctx context.Context, grace time.Duration) error {
+	var users []models.User
+	if err := storage.DB.WithContext(ctx).Unscoped().
+		Where("deleted_at <= ? AND erased_at IS NULL", time.Now().Add(-grace)).
+		Limit(100).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err := handlers.EraseUser(ctx, user, func(tx *gorm.DB, metadata audit.Metadata) error {
+			return utils.WriteAudit(ctx, tx, utils.SystemAuditEntry("job", "erase_user", "user", user.ID, metadata))
+		})
+		// apierr.UserErased — данные уже стёрты через purge
+		if err != nil && !errors.Is(err, apierr.UserErased) {
+			slog.Error("Ошибка при стирании данных пользователя", "user_id", user.ID, "error", err)
+		}
+	}
+	return nil
+}
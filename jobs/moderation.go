@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"Blog/audit"
+	"Blog/models"
+	"Blog/storage"
+	"Blog/utils"
+	"context"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+// LiftExpiredBlocks снимает блокировки, срок которых истёк. Доступ
+// возвращается и без неё — RequireAuth сравнивает срок сам, — задача
+// приводит в порядок статус и пишет снятие в журнал аудита.
+func LiftExpiredBlocks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := liftExpiredBlocks(ctx); err != nil {
+			slog.Error("Ошибка при снятии истёкших блокировок", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func liftExpiredBlocks(ctx context.Context) error {
+	var users []models.User
+	if err := storage.DB.WithContext(ctx).
+		Where("status <> ? AND status_until <= ?", models.StatusActive, time.Now()).
+		Limit(100).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		before := user
+		err := storage.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// блокировку могли продлить, пока шла выборка
+			res := tx.Model(&models.User{}).
+				Where("id = ? AND status = ? AND status_until <= ?", user.ID, user.Status, time.Now()).
+				Updates(map[string]any{"status": models.StatusActive, "status_reason": "", "status_until": nil})
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			user.Status, user.StatusReason, user.StatusUntil = models.StatusActive, "", nil
+			return utils.WriteAudit(ctx, tx, utils.SystemAuditEntry("job", "lift_block", "user", user.ID,
+				audit.Metadata{Changes: audit.Diff(before, user)}))
+		})
+		if err != nil {
+			slog.Error("Ошибка при снятии блокировки", "user_id", user.ID, "error", err)
+		}
+	}
+	return nil
+}
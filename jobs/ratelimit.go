@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"Blog/ratelimit"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// CleanupRateLimitBuckets удаляет ведра, которые давно полны и не нужны.
+// Час с запасом больше времени пополнения любой разумной политики.
+func CleanupRateLimitBuckets(ctx context.Context, buckets *ratelimit.Postgres, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := buckets.Cleanup(ctx, time.Hour); err != nil {
+				slog.Error("Ошибка при очистке ведер rate limit", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"Blog/handlers"
+	"Blog/models"
+	"Blog/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// CleanupExpiredUploads периодически удаляет брошенные загрузки по частям.
+func CleanupExpiredUploads(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var uploads []models.MediaUpload
+			if err := storage.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Limit(100).Find(&uploads).Error; err != nil {
+				slog.Error("Ошибка при поиске просроченных загрузок", "error", err)
+				continue
+			}
+			for _, upload := range uploads {
+				if err := handlers.DiscardUpload(ctx, upload); err != nil {
+					slog.Error("Ошибка при удалении просроченной загрузки", "upload_id", upload.ID, "error", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"log/slog"
+	"time"
+)
+
+// GormLogger пишет запросы GORM через slog с логгером из контекста запроса.
+// Ошибки — error, запросы дольше SlowThreshold — warn, остальные — debug.
+type GormLogger struct {
+	SlowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copy := *l
+	copy.level = level
+	return &copy
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed)}
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		logger.ErrorContext(ctx, "db query failed", append(attrs(), slog.String("error", err.Error()))...)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.level >= gormlogger.Warn:
+		logger.WarnContext(ctx, "slow db query", attrs()...)
+	case logger.Enabled(ctx, slog.LevelDebug):
+		logger.DebugContext(ctx, "db query", attrs()...)
+	}
+}
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"Blog/config"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	Default Sender = LogSender{}
+	from           = config.Default().Mail.From
+	siteURL        = config.Default().Mail.SiteURL
+)
+
+func Init(cfg config.Mail) {
+	from = cfg.From
+	siteURL = cfg.SiteURL
+	switch cfg.Driver {
+	case "smtp":
+		Default = &SMTP{
+			Addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+			Host:     cfg.Host,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}
+	default:
+		Default = LogSender{}
+	}
+}
+
+// LogSender ничего не отправляет, а пишет письмо в лог; для разработки.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Письмо", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// SMTP отправляет письма через сервер с STARTTLS (если он его предлагает)
+// и PLAIN-аутентификацией.
+type SMTP struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return fmt.Errorf("некорректный адрес получателя: %w", err)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("некорректный mail.from: %w", err)
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Addr, auth, sender.Address, []string{msg.To}, encode(msg))
+}
+
+func encode(msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(msg.Body))
+	qp.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"Blog/i18n"
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Шаблон письма — файл templates/<язык>/<имя>.tmpl с блоками "subject" и "body".
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// язык → имя письма → шаблон; каждый файл разбирается отдельно, потому что
+// блоки subject и body у всех писем называются одинаково
+var templates = map[string]map[string]*template.Template{}
+
+func init() {
+	for _, lang := range i18n.Supported {
+		files, err := fs.Glob(templateFiles, "templates/"+lang+"/*.tmpl")
+		if err != nil {
+			panic(err)
+		}
+		templates[lang] = map[string]*template.Template{}
+		for _, file := range files {
+			t, err := template.ParseFS(templateFiles, file)
+			if err != nil {
+				panic(err)
+			}
+			templates[lang][strings.TrimSuffix(path.Base(file), ".tmpl")] = t
+		}
+	}
+}
+
+// Render собирает письмо на языке lang. Если перевода письма нет,
+// используется язык по умолчанию.
+func Render(lang, name string, data map[string]any) (Message, error) {
+	t, ok := templates[lang][name]
+	if !ok {
+		t, ok = templates[i18n.Default][name]
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("шаблон письма %q не найден", name)
+	}
+
+	vars := map[string]any{"SiteURL": siteURL}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", vars); err != nil {
+		return Message{}, err
+	}
+	if err := t.ExecuteTemplate(&body, "body", vars); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: strings.TrimSpace(subject.String()), Body: strings.TrimSpace(body.String()) + "\n"}, nil
+}
+
+// AbsoluteURL дополняет ссылку от корня сайта адресом mail.site_url: в
+// письме относительная ссылка не откроется.
+func AbsoluteURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return strings.TrimSuffix(siteURL, "/") + link
+	}
+	return link
+}
+
+// SendTemplate рендерит шаблон и отправляет его через Default.
+func SendTemplate(ctx context.Context, to, lang, name string, data map[string]any) error {
+	msg, err := Render(lang, name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return Default.Send(ctx, msg)
+}
@@ -1,22 +1,23 @@
 package main
 
 import (
-	"Blog/migrate"
-	"Blog/routes"
-	"Blog/storage"
-	"github.com/gin-gonic/gin"
+	"Blog/cli"
+	"Blog/config"
+	"fmt"
+	"os"
 )
 
 func main() {
 
-	r := gin.Default()
-	r.Static("/uploads", "./uploads")
-	storage.ConnectDB()
-	migrate.RunMigrations()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
 
-	routes.RegisterUserRoutes(r)
-	routes.AuthRoutes(r)
-
-	r.Run(":8080")
+	if err := cli.Run(cfg, args); err != nil {
+		fmt.Println("Ошибка:", err)
+		os.Exit(1)
+	}
 
 }
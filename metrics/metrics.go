@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "blog"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Login attempts by result (success, failure).",
+	}, []string{"result"})
+
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_registrations_total",
+		Help:      "Successful user registrations.",
+	})
+
+	TokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_token_refreshes_total",
+		Help:      "Access token refreshes by result (success, failure).",
+	}, []string{"result"})
+
+	Uploads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Stored uploads by kind (avatar, media).",
+	}, []string{"kind"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by rate limit policy.",
+	}, []string{"policy"})
+
+	AuditWriteFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_write_failures_total",
+		Help:      "Audit log entries that could not be written.",
+	})
+
+	AuditForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_forwarded_total",
+		Help:      "Audit log entries delivered to external sinks by sink name.",
+	}, []string{"sink"})
+
+	AuditForwardFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_forward_failures_total",
+		Help:      "Failed deliveries of audit log batches by sink name.",
+	}, []string{"sink"})
+)
+
+// RegisterDB публикует статистику пула соединений (открытые, занятые, ожидания).
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "blog"))
+}
+
+func Result(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
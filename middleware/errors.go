@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"Blog/apierr"
+	"Blog/i18n"
+	"Blog/logging"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+)
+
+// Errors превращает последнюю ошибку из c.Errors в ответ единого формата
+// на языке запроса:
+//
+//	{"success": false, "error": {"code": "...", "message": "...", "fields": {...}, "request_id": "..."}}
+//
+// Обработчики только вызывают c.Error(apierr.X) и выходят. Ошибки не из
+// каталога отдаются как internal_error, а их текст пишется в лог.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		var apiErr *apierr.Error
+		if !errors.As(last.Err, &apiErr) {
+			apiErr = apierr.Internal.Wrap(last.Err)
+		}
+		if apiErr.Status >= 500 {
+			logging.FromContext(c).Error("Ошибка обработки запроса", "code", apiErr.Code, "error", apiErr)
+		}
+
+		lang := i18n.FromContext(c)
+		message, ok := i18n.Lookup(lang, "errors."+apiErr.Code)
+		if !ok {
+			message = apiErr.Message
+		}
+		fields := apiErr.Fields
+		if len(fields) == 0 {
+			fields = i18n.ValidationErrors(lang, apiErr)
+		}
+
+		body := gin.H{
+			"code":       apiErr.Code,
+			"message":    message,
+			"request_id": c.GetString("request_id"),
+		}
+		if len(fields) > 0 {
+			body["fields"] = fields
+		}
+		if len(apiErr.Details) > 0 {
+			body["details"] = apiErr.Details
+		}
+		c.JSON(apiErr.Status, gin.H{
+			"success": false,
+			"error":   body,
+		})
+	}
+}
+
+// Recover ловит панику обработчика и отдаёт её в Errors как internal_error.
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		c.Error(apierr.Internal.Wrap(fmt.Errorf("panic: %v", recovered)))
+		c.Abort()
+	})
+}
+
+// NoRoute и NoMethod подключаются к движку, чтобы и 404/405 были в общем формате.
+func NoRoute(c *gin.Context) {
+	c.Error(apierr.RouteNotFound)
+}
+
+func NoMethod(c *gin.Context) {
+	c.Error(apierr.MethodNotAllowed)
+}
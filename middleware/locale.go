@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"Blog/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// ключ gin.Context: язык выбран явно через ?lang= и важнее настроек пользователя
+const langExplicitKey = "lang_explicit"
+
+// Locale выбирает язык ответа: ?lang=, иначе Accept-Language. Для
+// авторизованных запросов RequireAuth потом подставляет язык из профиля.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Language")
+
+		lang := i18n.Normalize(c.Query("lang"))
+		if lang != "" {
+			c.Set(langExplicitKey, true)
+		} else {
+			lang = i18n.Negotiate(c.GetHeader("Accept-Language"))
+		}
+		setLanguage(c, lang)
+		c.Next()
+	}
+}
+
+func setLanguage(c *gin.Context, lang string) {
+	c.Set(i18n.ContextKey, lang)
+	c.Header("Content-Language", lang)
+}
+
+// applyUserLanguage переключает язык на предпочитаемый пользователем, если он задан.
+func applyUserLanguage(c *gin.Context, lang string) {
+	if c.GetBool(langExplicitKey) {
+		return
+	}
+	if i18n.IsSupported(lang) {
+		setLanguage(c, lang)
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"Blog/logging"
+	"Blog/utils"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID берёт X-Request-ID клиента (или генерирует новый), возвращает его
+// в ответе и кладёт в контекст логгер запроса.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = utils.RandomToken(16)
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := logging.With(c.Request.Context(),
+			slog.String("request_id", id),
+			slog.String("route", route),
+		)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// AccessLog пишет по строке на запрос после его обработки.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		)
+	}
+}
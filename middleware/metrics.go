@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"Blog/metrics"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// Metrics считает запросы по шаблону маршрута (c.FullPath), а не по сырому URL,
+// чтобы /user/1 и /user/2 попадали в одну серию.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -1,34 +1,59 @@
 package middleware
 
 import (
+	"Blog/apierr"
+	"Blog/logging"
 	"Blog/models"
 	"Blog/storage"
 	"Blog/utils"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"net/http"
+	"gorm.io/gorm"
+	"log/slog"
 	"strconv"
 	"strings"
 )
 
-func RequireAuth() gin.HandlerFunc {
+func RequireAuth(tokens *utils.Tokens) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			utils.RespondError(c, http.StatusUnauthorized, "Требуется access токен")
+			c.Error(apierr.TokenRequired)
 			c.Abort()
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		userID, err := utils.ParseAccessToken(tokenStr)
+		userID, err := tokens.ParseAccessToken(tokenStr)
 		if err != nil {
-			utils.RespondError(c, http.StatusUnauthorized, err.Error())
+			c.Error(apierr.InvalidToken.Wrap(err))
+			c.Abort()
+			return
+		}
+
+		// пользователь читается на каждый запрос, чтобы блокировка и удаление
+		// действовали сразу, а не после истечения уже выданного токена
+		var user models.User
+		if err := storage.DB.WithContext(c).Select("id", "language", "status", "status_reason", "status_until").
+			First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.Error(apierr.AccountNotFound)
+			} else {
+				c.Error(apierr.Internal.Wrap(err))
+			}
+			c.Abort()
+			return
+		}
+		if err := utils.AccountBlocked(user); err != nil {
+			c.Error(err)
 			c.Abort()
 			return
 		}
 
 		c.Set("user_id", userID)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.Uint64("user_id", uint64(userID))))
+		applyUserLanguage(c, user.Language)
 		c.Next()
 	}
 }
@@ -40,20 +65,20 @@ func CanEditOrAdmin() gin.HandlerFunc {
 		idParam := c.Param("id")
 		targetID, err := strconv.Atoi(idParam)
 		if err != nil {
-			utils.RespondError(c, http.StatusBadRequest, "Некорректный ID")
+			c.Error(apierr.InvalidID)
 			c.Abort()
 			return
 		}
 
 		var user models.User
-		if err := storage.DB.First(&user, authUserID).Error; err != nil {
-			utils.RespondError(c, http.StatusUnauthorized, "Пользователь не найден")
+		if err := storage.DB.WithContext(c).First(&user, authUserID).Error; err != nil {
+			c.Error(apierr.AccountNotFound)
 			c.Abort()
 			return
 		}
 
 		if uint(targetID) != authUserID && user.Role != "admin" {
-			utils.RespondError(c, http.StatusForbidden, "Нет прав на редактирование")
+			c.Error(apierr.EditForbidden)
 			c.Abort()
 			return
 		}
@@ -66,14 +91,14 @@ func RequireAdmin() gin.HandlerFunc {
 		userID := c.GetUint("user_id")
 
 		var user models.User
-		if err := storage.DB.First(&user, userID).Error; err != nil {
-			utils.RespondError(c, http.StatusUnauthorized, "Пользователь не найден")
+		if err := storage.DB.WithContext(c).First(&user, userID).Error; err != nil {
+			c.Error(apierr.AccountNotFound)
 			c.Abort()
 			return
 		}
 
 		if user.Role != "admin" {
-			utils.RespondError(c, http.StatusForbidden, "Требуются права администратора")
+			c.Error(apierr.AdminRequired)
 			c.Abort()
 			return
 		}
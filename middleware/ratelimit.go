@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"Blog/apierr"
+	"Blog/config"
+	"Blog/logging"
+	"Blog/metrics"
+	"Blog/ratelimit"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+// RateLimiter ограничивает запросы по политикам из конфигурации.
+type RateLimiter struct {
+	policies map[string]config.RateLimitPolicy
+	backend  ratelimit.Backend
+}
+
+// NewRateLimiter — ограничитель с политиками cfg и ведрами в backend.
+func NewRateLimiter(cfg config.RateLimit, backend ratelimit.Backend) *RateLimiter {
+	return &RateLimiter{policies: cfg.Policies(), backend: backend}
+}
+
+// Limit ограничивает запросы по политике name (login, register, refresh,
+// upload, api). Для ключа user middleware должен стоять после RequireAuth,
+// иначе лимит считается по IP.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	policy := l.policies[name]
+	return func(c *gin.Context) {
+		if policy.PerMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		limit := ratelimit.Limit{Rate: policy.PerMinute / 60, Burst: policy.Burst}
+		res, err := l.backend.Allow(c, name+":"+rateLimitKey(c, policy.Key), limit)
+		if err != nil {
+			// недоступное хранилище лимитов не должно класть весь API
+			logging.FromContext(c).Error("Ошибка ограничителя запросов", "policy", name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(max(res.Remaining, 0)))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(res.Reset.Seconds())))
+
+		if !res.Allowed {
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(res.RetryAfter.Seconds())))
+			c.Error(apierr.RateLimited)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context, kind string) string {
+	switch kind {
+	case "user":
+		if userID := c.GetUint("user_id"); userID != 0 {
+			return "user:" + strconv.FormatUint(uint64(userID), 10)
+		}
+	case "token":
+		// храним хеш, а не сам токен
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,74 @@
+package migrate
+
+import (
+	"Blog/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+const usage = `использование: blog migrate [-dry-run] <команда>
+
+команды:
+  up            применить все новые миграции
+  down [N]      откатить N последних миграций (по умолчанию 1)
+  to VERSION    привести схему к версии VERSION (0 — откатить всё)
+  status        показать список миграций`
+
+// RunCLI выполняет подкоманду "migrate" с аргументами args.
+func RunCLI(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "только показать SQL, ничего не выполняя")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("не указана команда")
+	}
+
+	m := New(storage.DB)
+	m.DryRun = *dryRun
+
+	switch cmd := fs.Arg(0); cmd {
+	case "up":
+		return m.Up()
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			n, err := strconv.Atoi(fs.Arg(1))
+			if err != nil || n <= 0 {
+				return fmt.Errorf("некорректное число шагов %q", fs.Arg(1))
+			}
+			steps = n
+		}
+		return m.Down(steps)
+	case "to":
+		if fs.NArg() < 2 {
+			return errors.New("не указана версия")
+		}
+		version, err := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("некорректная версия %q", fs.Arg(1))
+		}
+		return m.To(version)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(m.Out, "%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("неизвестная команда %q", cmd)
+	}
+}
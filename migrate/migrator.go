@@ -1,21 +1,291 @@
 package migrate
 
 import (
-	"Blog/models"
-	"Blog/storage"
+	"embed"
 	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 )
 
-func RunMigrations() {
-	db := storage.DB
+//go:embed sql/*.sql
+var sqlFiles embed.FS
 
-	err := db.AutoMigrate(
-		&models.User{}, &models.AuditLog{},
-	)
+// ключ pg_advisory_lock, чтобы несколько экземпляров не мигрировали одновременно
+const lockID int64 = 0x426c6f674d6967
 
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	DB     *gorm.DB
+	DryRun bool
+	Out    io.Writer
+}
+
+func New(db *gorm.DB) *Migrator {
+	return &Migrator{DB: db, Out: os.Stdout}
+}
+
+// Load читает встроенные файлы вида 0001_name.up.sql / 0001_name.down.sql.
+func Load() ([]Migration, error) {
+	entries, err := sqlFiles.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		match := fileNamePattern.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции %q", e.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := sqlFiles.ReadFile(path.Join("sql", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("у миграции %d два разных имени: %s и %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет up-файла", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *Migrator) Up() error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	return m.To(migrations[len(migrations)-1].Version)
+}
+
+// Down откатывает steps последних применённых миграций.
+func (m *Migrator) Down(steps int) error {
+	return m.withLock(func(conn *gorm.DB, migrations []Migration, applied map[int64]SchemaMigration) error {
+		versions := appliedVersions(applied)
+		if steps > len(versions) {
+			steps = len(versions)
+		}
+		for i := len(versions) - 1; i >= len(versions)-steps; i-- {
+			if err := m.down(conn, findMigration(migrations, versions[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To приводит схему к версии version: применяет недостающие миграции до неё
+// и откатывает все, что новее.
+func (m *Migrator) To(version int64) error {
+	return m.withLock(func(conn *gorm.DB, migrations []Migration, applied map[int64]SchemaMigration) error {
+		if version != 0 && findMigration(migrations, version).Version == 0 {
+			return fmt.Errorf("миграция %d не найдена", version)
+		}
+
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			if err := m.down(conn, findMigration(migrations, versions[i])); err != nil {
+				return err
+			}
+		}
+
+		for _, mig := range migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.up(conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(migrations))
+	for _, mig := range migrations {
+		rec, ok := applied[mig.Version]
+		result = append(result, Status{Migration: mig, Applied: ok, AppliedAt: rec.AppliedAt})
+	}
+	return result, nil
+}
+
+// Pending возвращает число известных, но ещё не применённых миграций.
+func (m *Migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) withLock(fn func(conn *gorm.DB, migrations []Migration, applied map[int64]SchemaMigration) error) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	// advisory lock живёт на уровне соединения, поэтому вся работа идёт в одном
+	return m.DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+
+		if !m.DryRun {
+			if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+				return err
+			}
+		}
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		return fn(conn, migrations, applied)
+	})
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	applied := map[int64]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+func (m *Migrator) up(conn *gorm.DB, mig Migration) error {
+	fmt.Fprintf(m.Out, "-- up %04d_%s\n", mig.Version, mig.Name)
+	if m.DryRun {
+		fmt.Fprintln(m.Out, mig.Up)
+		return nil
+	}
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mig.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("миграция %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) down(conn *gorm.DB, mig Migration) error {
+	if mig.Version == 0 {
+		return fmt.Errorf("применённая миграция не найдена среди файлов, откат невозможен")
+	}
+	if mig.Down == "" {
+		return fmt.Errorf("у миграции %04d_%s нет down-файла", mig.Version, mig.Name)
+	}
+
+	fmt.Fprintf(m.Out, "-- down %04d_%s\n", mig.Version, mig.Name)
+	if m.DryRun {
+		fmt.Fprintln(m.Out, mig.Down)
+		return nil
+	}
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mig.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, mig.Version).Error
+	})
 	if err != nil {
-		panic("Ошибка миграции: " + err.Error())
+		return fmt.Errorf("откат %04d_%s: %w", mig.Version, mig.Name, err)
 	}
+	return nil
+}
 
-	fmt.Println("Миграция завершена")
+func appliedVersions(applied map[int64]SchemaMigration) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func findMigration(migrations []Migration, version int64) Migration {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m
+		}
+	}
+	return Migration{}
 }
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    nickname      TEXT NOT NULL UNIQUE,
+    email         TEXT NOT NULL UNIQUE,
+    password      TEXT NOT NULL,
+    role          VARCHAR(20) DEFAULT 'user',
+    avatar_url    TEXT,
+    registered_at TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT,
+    action     TEXT,
+    object     TEXT,
+    object_id  BIGINT,
+    timestamp  TIMESTAMPTZ,
+    ip         TEXT,
+    user_agent TEXT,
+    metadata   TEXT
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS media_upload_parts;
+DROP TABLE IF EXISTS media_uploads;
+DROP TABLE IF EXISTS media_references;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS media (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL,
+    file_name     TEXT NOT NULL,
+    key           TEXT NOT NULL,
+    content_type  TEXT NOT NULL,
+    size          BIGINT NOT NULL,
+    thumbnail_key TEXT,
+    width         BIGINT,
+    height        BIGINT,
+    created_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON media (user_id);
+
+CREATE TABLE IF NOT EXISTS media_references (
+    id         BIGSERIAL PRIMARY KEY,
+    media_id   BIGINT NOT NULL,
+    object     TEXT NOT NULL,
+    object_id  BIGINT NOT NULL,
+    published  BOOLEAN,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_references_media_id ON media_references (media_id);
+
+CREATE TABLE IF NOT EXISTS media_uploads (
+    id           VARCHAR(64) PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    file_name    TEXT NOT NULL,
+    content_type TEXT,
+    size         BIGINT NOT NULL,
+    received     BIGINT NOT NULL DEFAULT 0,
+    expires_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_uploads_user_id ON media_uploads (user_id);
+CREATE INDEX IF NOT EXISTS idx_media_uploads_expires_at ON media_uploads (expires_at);
+
+CREATE TABLE IF NOT EXISTS media_upload_parts (
+    id           BIGSERIAL PRIMARY KEY,
+    upload_id    VARCHAR(64) NOT NULL,
+    start_offset BIGINT NOT NULL,
+    size         BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_upload_parts_upload_id ON media_upload_parts (upload_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_logs_ip;
+DROP INDEX IF EXISTS idx_audit_logs_object;
+DROP INDEX IF EXISTS idx_audit_logs_action;
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+DROP INDEX IF EXISTS idx_audit_logs_timestamp_id;
@@ -0,0 +1,9 @@
+-- Индексы под фильтры и курсорную пагинацию журнала аудита: все запросы
+-- сортируют по (timestamp, id) по убыванию.
+-- На уже большой таблице их лучше заранее создать вручную с CONCURRENTLY,
+-- тогда IF NOT EXISTS пропустит их здесь.
+CREATE INDEX IF NOT EXISTS idx_audit_logs_timestamp_id ON audit_logs (timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_object ON audit_logs (object, object_id, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_ip ON audit_logs (ip, timestamp DESC, id DESC);
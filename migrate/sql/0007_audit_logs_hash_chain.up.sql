@@ -0,0 +1,4 @@
+-- Цепочка хешей журнала аудита. Старые записи остаются с пустыми hash и
+-- prev_hash; цепочка начинается с первой записи после миграции.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_logs_metadata;
+ALTER TABLE audit_logs ALTER COLUMN metadata DROP NOT NULL;
+ALTER TABLE audit_logs ALTER COLUMN metadata DROP DEFAULT;
+ALTER TABLE audit_logs ALTER COLUMN metadata TYPE TEXT
+    USING CASE WHEN jsonb_typeof(metadata) = 'string' THEN metadata #>> '{}' ELSE metadata::text END;
@@ -0,0 +1,8 @@
+-- Старые текстовые metadata становятся строками JSON: хеши записей в цепочке
+-- считаются от JSON-значения и поэтому не меняются.
+ALTER TABLE audit_logs ALTER COLUMN metadata TYPE JSONB USING to_jsonb(COALESCE(metadata, ''));
+ALTER TABLE audit_logs ALTER COLUMN metadata SET DEFAULT '{}';
+ALTER TABLE audit_logs ALTER COLUMN metadata SET NOT NULL;
+
+-- фильтр по изменённым полям: metadata @> '{"changed": ["email"]}'
+CREATE INDEX IF NOT EXISTS idx_audit_logs_metadata ON audit_logs USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,13 @@
+-- Записи аудита в асинхронном режиме: пишутся в транзакции изменения,
+-- в audit_logs их переносит фоновый процесс.
+CREATE TABLE IF NOT EXISTS audit_outbox (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT,
+    action     TEXT,
+    object     TEXT,
+    object_id  BIGINT,
+    timestamp  TIMESTAMPTZ,
+    ip         TEXT,
+    user_agent TEXT,
+    metadata   JSONB NOT NULL DEFAULT '{}'
+);
@@ -0,0 +1,33 @@
+-- Заархивированные записи обратно не возвращаются.
+DROP TABLE IF EXISTS audit_archives;
+
+ALTER TABLE audit_logs RENAME TO audit_logs_partitioned;
+ALTER SEQUENCE audit_logs_id_seq OWNED BY NONE;
+
+CREATE TABLE audit_logs (
+    id         BIGINT PRIMARY KEY DEFAULT nextval('audit_logs_id_seq'),
+    user_id    BIGINT,
+    action     TEXT,
+    object     TEXT,
+    object_id  BIGINT,
+    timestamp  TIMESTAMPTZ,
+    ip         TEXT,
+    user_agent TEXT,
+    metadata   JSONB NOT NULL DEFAULT '{}',
+    prev_hash  TEXT NOT NULL DEFAULT '',
+    hash       TEXT NOT NULL DEFAULT ''
+);
+ALTER SEQUENCE audit_logs_id_seq OWNED BY audit_logs.id;
+
+INSERT INTO audit_logs SELECT id, user_id, action, object, object_id, timestamp, ip, user_agent, metadata, prev_hash, hash
+FROM audit_logs_partitioned;
+
+DROP TABLE audit_logs_partitioned;
+DROP FUNCTION IF EXISTS audit_logs_create_partition(DATE);
+
+CREATE INDEX idx_audit_logs_timestamp_id ON audit_logs (timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_object ON audit_logs (object, object_id, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_ip ON audit_logs (ip, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_metadata ON audit_logs USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,77 @@
+-- Помесячные секции audit_logs по timestamp: старые месяцы архивируются и
+-- удаляются целиком (см. audit.EnforceRetention). Первичный ключ
+-- секционированной таблицы обязан включать ключ секционирования.
+
+-- Секция на месяц, в который попадает month; границы — по UTC.
+CREATE OR REPLACE FUNCTION audit_logs_create_partition(month DATE) RETURNS VOID AS $$
+DECLARE
+    start DATE := date_trunc('month', month);
+BEGIN
+    EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+        'audit_logs_' || to_char(start, 'YYYY_MM'),
+        start::timestamp AT TIME ZONE 'UTC',
+        (start + INTERVAL '1 month')::timestamp AT TIME ZONE 'UTC');
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE audit_logs RENAME TO audit_logs_unpartitioned;
+ALTER SEQUENCE audit_logs_id_seq OWNED BY NONE;
+
+CREATE TABLE audit_logs (
+    id         BIGINT NOT NULL DEFAULT nextval('audit_logs_id_seq'),
+    user_id    BIGINT,
+    action     TEXT,
+    object     TEXT,
+    object_id  BIGINT,
+    timestamp  TIMESTAMPTZ NOT NULL,
+    ip         TEXT,
+    user_agent TEXT,
+    metadata   JSONB NOT NULL DEFAULT '{}',
+    prev_hash  TEXT NOT NULL DEFAULT '',
+    hash       TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (id, timestamp)
+) PARTITION BY RANGE (timestamp);
+ALTER SEQUENCE audit_logs_id_seq OWNED BY audit_logs.id;
+
+-- старые записи без времени и всё, для чего секцию не успели создать
+CREATE TABLE audit_logs_default PARTITION OF audit_logs DEFAULT;
+
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    SELECT date_trunc('month', min(timestamp) AT TIME ZONE 'UTC') INTO month FROM audit_logs_unpartitioned;
+    month := COALESCE(month, date_trunc('month', now() AT TIME ZONE 'UTC'));
+    WHILE month <= now() AT TIME ZONE 'UTC' + INTERVAL '2 months' LOOP
+        PERFORM audit_logs_create_partition(month);
+        month := month + INTERVAL '1 month';
+    END LOOP;
+END $$;
+
+INSERT INTO audit_logs (id, user_id, action, object, object_id, timestamp, ip, user_agent, metadata, prev_hash, hash)
+SELECT id, user_id, action, object, object_id, COALESCE(timestamp, 'epoch'), ip, user_agent, metadata, prev_hash, hash
+FROM audit_logs_unpartitioned;
+
+DROP TABLE audit_logs_unpartitioned;
+
+CREATE INDEX idx_audit_logs_timestamp_id ON audit_logs (timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_object ON audit_logs (object, object_id, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_ip ON audit_logs (ip, timestamp DESC, id DESC);
+CREATE INDEX idx_audit_logs_metadata ON audit_logs USING GIN (metadata jsonb_path_ops);
+-- последняя запись цепочки ищется по id
+CREATE INDEX idx_audit_logs_id ON audit_logs (id DESC);
+
+-- Что и куда уже заархивировано. last_hash последнего архива — начало
+-- цепочки, оставшейся в audit_logs.
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    file       TEXT NOT NULL,
+    entries    BIGINT NOT NULL,
+    first_id   BIGINT NOT NULL,
+    last_id    BIGINT NOT NULL,
+    last_hash  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+-- До какой записи журнала дошла пересылка в каждый внешний получатель.
+CREATE TABLE IF NOT EXISTS audit_sink_cursors (
+    sink       TEXT PRIMARY KEY,
+    last_id    BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
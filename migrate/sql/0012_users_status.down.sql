@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_status_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
@@ -0,0 +1,8 @@
+-- Блокировка аккаунта: suspended — временная мера, banned — бан. Пустой
+-- status_until — бессрочно.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_until TIMESTAMPTZ;
+
+-- для снятия истёкших блокировок
+CREATE INDEX IF NOT EXISTS idx_users_status_until ON users (status_until) WHERE status <> 'active';
@@ -0,0 +1,3 @@
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS redacted_hash;
+DROP INDEX IF EXISTS idx_users_pending_erasure;
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
@@ -0,0 +1,8 @@
+-- Стирание личных данных удалённых пользователей. erased_at — когда
+-- данные стёрты; строка пользователя остаётся, на неё ссылаются журнал и файлы.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_users_pending_erasure ON users (deleted_at) WHERE deleted_at IS NOT NULL AND erased_at IS NULL;
+
+-- хеш записи журнала после стирания личных данных; hash остаётся прежним,
+-- на него ссылается следующая запись
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS redacted_hash TEXT NOT NULL DEFAULT '';
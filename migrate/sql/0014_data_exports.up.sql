@@ -0,0 +1,19 @@
+-- Выгрузка личных данных по запросу пользователя: архив собирает фоновая
+-- задача, файл лежит в хранилище до expires_at, потом удаляется, а запись
+-- остаётся со статусом expired.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    status       VARCHAR(16) NOT NULL DEFAULT 'pending',
+    key          TEXT NOT NULL DEFAULT '',
+    size         BIGINT NOT NULL DEFAULT 0,
+    error        TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at   TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports (id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports (expires_at);
@@ -0,0 +1,40 @@
+-- Журнал аудита только дописывается и на уровне базы, а не только через
+-- хуки модели AuditLog. Разрешено два изменения:
+--   * стирание личных данных (audit.RedactUser): меняются только ip,
+--     user_agent, metadata и redacted_hash, и redacted_hash не пустой;
+--   * удаление уже заархивированных записей из секции по умолчанию
+--     (audit.EnforceRetention): id не больше last_id последнего архива.
+-- Месячные секции удаляются DROP TABLE, строковые триггеры его не видят.
+-- Владелец таблицы может снять триггер; приложению стоит работать под
+-- ролью, которая не владеет audit_logs.
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF NEW.id IS DISTINCT FROM OLD.id
+            OR NEW.user_id IS DISTINCT FROM OLD.user_id
+            OR NEW.action IS DISTINCT FROM OLD.action
+            OR NEW.object IS DISTINCT FROM OLD.object
+            OR NEW.object_id IS DISTINCT FROM OLD.object_id
+            OR NEW.timestamp IS DISTINCT FROM OLD.timestamp
+            OR NEW.prev_hash IS DISTINCT FROM OLD.prev_hash
+            OR NEW.hash IS DISTINCT FROM OLD.hash
+            OR NEW.redacted_hash = '' THEN
+            RAISE EXCEPTION 'audit_logs: запись % можно менять только при стирании личных данных', OLD.id
+                USING ERRCODE = 'insufficient_privilege';
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    IF OLD.id > (SELECT COALESCE(max(last_id), 0) FROM audit_archives) THEN
+        RAISE EXCEPTION 'audit_logs: запись % не заархивирована и не может быть удалена', OLD.id
+            USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- триггер на секционированной таблице (Postgres 13+) переходит на все секции,
+-- в том числе созданные позже audit_logs_create_partition
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
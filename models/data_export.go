@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// DataExport — выгрузка всех данных пользователя одним ZIP-архивом.
+type DataExport struct {
+	ID          uint       `gorm:"primary_key"`
+	UserID      uint       `gorm:"index;not null"`
+	Status      string     `gorm:"type:varchar(16);not null;default:'pending'"`
+	Key         string     `gorm:"not null;default:''"` // ключ архива в хранилище, пока не готов — пусто
+	Size        int64      `gorm:"not null;default:0"`
+	Error       string     `gorm:"not null;default:''"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	StartedAt   *time.Time // когда задача взяла выгрузку в работу
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"` // после этого архив удаляется
+}
+
+// Статусы выгрузки.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired" // архив удалён по истечении срока хранения
+)
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type Media struct {
+	ID           uint   `gorm:"primary_key"`
+	UserID       uint   `gorm:"index;not null"` // владелец
+	FileName     string `gorm:"not null"`
+	Key          string `gorm:"not null"` // ключ в хранилище
+	ContentType  string `gorm:"not null"`
+	Size         int64  `gorm:"not null"`
+	ThumbnailKey string // пусто, если это не картинка
+	Width        int
+	Height       int
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// MediaReference — где используется файл (например, object = "post").
+// Файл нельзя удалить, пока на него ссылается опубликованный объект.
+type MediaReference struct {
+	ID        uint   `gorm:"primary_key"`
+	MediaID   uint   `gorm:"index;not null"`
+	Object    string `gorm:"not null"`
+	ObjectID  uint   `gorm:"not null"`
+	Published bool
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// MediaUpload — незавершённая загрузка по частям.
+type MediaUpload struct {
+	ID          string `gorm:"primary_key;type:varchar(64)"`
+	UserID      uint   `gorm:"index;not null"`
+	FileName    string `gorm:"not null"`
+	ContentType string
+	Size        int64     `gorm:"not null"` // ожидаемый размер файла
+	Received    int64     `gorm:"not null;default:0"`
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+type MediaUploadPart struct {
+	ID          uint   `gorm:"primary_key"`
+	UploadID    string `gorm:"index;type:varchar(64);not null"`
+	StartOffset int64  `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+}
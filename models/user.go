@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"gorm.io/gorm"
 	"time"
 )
@@ -9,12 +11,37 @@ type User struct {
 	ID           uint      `gorm:"primary_key"`
 	Nickname     string    `gorm:"unique;not null"`
 	Email        string    `gorm:"unique;not null"`
-	Password     string    `gorm:"not null"`
+	Password     string    `gorm:"not null" audit:"redact"`
 	Role         string    `gorm:"type:varchar(20);default:'user'"`
 	AvatarURL    string    `gorm:"type:text"`
+	AvatarKey    string    `gorm:"type:text"`                           // префикс файлов аватара в хранилище
+	Language     string    `gorm:"type:varchar(8);not null;default:''"` // пусто — по Accept-Language
 	RegisteredAt time.Time `gorm:"autoCreateTime"`
 
+	// блокировка администратором, см. Blocked
+	Status       string     `gorm:"type:varchar(16);not null;default:'active'"`
+	StatusReason string     `gorm:"type:text;not null;default:''"`
+	StatusUntil  *time.Time // nil — бессрочно
+
 	gorm.DeletedAt `gorm:"index"`
+	// когда личные данные удалённого пользователя стёрты безвозвратно
+	ErasedAt *time.Time
+}
+
+// Статусы аккаунта.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+)
+
+// Blocked сообщает, действует ли блокировка сейчас. Истёкшая блокировка уже
+// не действует, даже если фоновая задача ещё не вернула статус active.
+func (u User) Blocked(now time.Time) bool {
+	if u.Status == "" || u.Status == StatusActive {
+		return false
+	}
+	return u.StatusUntil == nil || now.Before(*u.StatusUntil)
 }
 
 type AuditLog struct {
@@ -27,5 +54,49 @@ type AuditLog struct {
 
 	IP        string
 	UserAgent string
-	Metadata  string
+	Metadata  json.RawMessage `gorm:"type:jsonb;not null;default:'{}'"` // см. audit.Metadata
+
+	// цепочка хешей, см. пакет audit; у записей до её появления пусто
+	PrevHash string `gorm:"not null;default:''"`
+	Hash     string `gorm:"not null;default:''"`
+	// хеш содержимого после стирания личных данных, см. audit.RedactUser
+	RedactedHash string `gorm:"not null;default:''"`
 }
+
+// AuditOutbox — запись журнала, ещё не перенесённая в цепочку
+// (асинхронный режим аудита).
+type AuditOutbox struct {
+	ID        uint `gorm:"primary_key"`
+	UserID    uint
+	Action    string
+	Object    string
+	ObjectID  uint
+	Timestamp time.Time
+
+	IP        string
+	UserAgent string
+	Metadata  json.RawMessage `gorm:"type:jsonb;not null;default:'{}'"`
+}
+
+func (AuditOutbox) TableName() string { return "audit_outbox" }
+
+// AuditArchive — выгруженная в файл и удалённая из базы часть журнала.
+type AuditArchive struct {
+	ID        uint `gorm:"primary_key"`
+	Name      string
+	File      string
+	Entries   int64
+	FirstID   uint
+	LastID    uint
+	LastHash  string
+	CreatedAt time.Time
+}
+
+// ErrAuditAppendOnly — записи журнала аудита нельзя менять и удалять:
+// это разорвёт цепочку хешей. В обход хуков то же проверяет триггер
+// audit_logs_append_only (миграция 0015).
+var ErrAuditAppendOnly = errors.New("журнал аудита только дополняется")
+
+func (AuditLog) BeforeUpdate(*gorm.DB) error { return ErrAuditAppendOnly }
+
+func (AuditLog) BeforeDelete(*gorm.DB) error { return ErrAuditAppendOnly }
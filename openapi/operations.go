@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"Blog/apierr"
+	"Blog/audit"
+	"Blog/dto"
+	"net/http"
+)
+
+type (
+	userData struct {
+		User dto.UserResponse `json:"user"`
+	}
+	authData struct {
+		User        dto.UserResponse `json:"user"`
+		AccessToken string           `json:"access_token"`
+	}
+	tokenData struct {
+		AccessToken string `json:"access_token"`
+	}
+	avatarData struct {
+		AvatarURL string            `json:"avatar_url"`
+		Avatars   map[string]string `json:"avatars"`
+	}
+	userListData struct {
+		Users []dto.UserResponse `json:"users"`
+		Page  int                `json:"page"`
+		Limit int                `json:"limit"`
+		Total int64              `json:"total"`
+	}
+	auditLogsData struct {
+		Logs       []dto.AuditLogResponse `json:"logs"`
+		NextCursor string                 `json:"next_cursor"`
+	}
+	dataExportData struct {
+		Export dto.DataExportResponse `json:"export"`
+	}
+	securityLogData struct {
+		Events     []dto.SecurityEventResponse `json:"events"`
+		NextCursor string                      `json:"next_cursor"`
+	}
+	auditVerifyData struct {
+		Valid  bool              `json:"valid"`
+		Report audit.ChainReport `json:"report"`
+	}
+	mediaData struct {
+		Media dto.MediaResponse `json:"media"`
+	}
+	mediaListData struct {
+		Media []dto.MediaResponse `json:"media"`
+		Page  int                 `json:"page"`
+		Limit int                 `json:"limit"`
+		Total int64               `json:"total"`
+		Quota struct {
+			Used  int64 `json:"used"`
+			Limit int64 `json:"limit"`
+		} `json:"quota"`
+	}
+	uploadData struct {
+		Upload dto.MediaUploadResponse `json:"upload"`
+	}
+	createUploadData struct {
+		Upload    dto.MediaUploadResponse `json:"upload"`
+		ChunkSize int64                   `json:"chunk_size"`
+	}
+	healthStatus struct {
+		Status string         `json:"status"`
+		Checks map[string]any `json:"checks,omitempty"`
+	}
+)
+
+var auditFilters = []Param{
+	{Name: "user_id", Type: "integer", Description: "Автор действия"},
+	{Name: "action", Description: "Одно или несколько действий через запятую"},
+	{Name: "object", Description: "Тип объекта: user, media, ..."},
+	{Name: "object_id", Type: "integer"},
+	{Name: "ip"},
+	{Name: "changed", Description: "Изменённые поля через запятую, например email"},
+	{Name: "from", Description: "Не раньше, RFC 3339"},
+	{Name: "to", Description: "Раньше, RFC 3339"},
+}
+
+var paging = []Param{
+	{Name: "page", Type: "integer", Description: "Номер страницы, с 1"},
+	{Name: "limit", Type: "integer", Description: "Размер страницы"},
+}
+
+// Operations — описание всех маршрутов API. Новый маршрут нужно добавить и
+// сюда, иначе `blog openapi check` завершится с ошибкой.
+var Operations = []Operation{
+	// Служебные
+	{Method: http.MethodGet, Path: "/healthz", Tag: "service", Summary: "Liveness: процесс жив", Plain: healthStatus{}},
+	{Method: http.MethodGet, Path: "/readyz", Tag: "service", Summary: "Readiness: база доступна, миграции применены",
+		Description: "Возвращает 503 с тем же телом, если сервис не готов.", Plain: healthStatus{}},
+	{Method: http.MethodGet, Path: "/metrics", Tag: "service", Summary: "Метрики Prometheus", Produces: "text/plain"},
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "service", Summary: "Этот документ", Plain: map[string]any{}},
+	{Method: http.MethodGet, Path: "/docs", Tag: "service", Summary: "Интерактивная документация", Produces: "text/html"},
+
+	// Аутентификация
+	{Method: http.MethodPost, Path: "/register", Tag: "auth", Summary: "Регистрация",
+		Description: "Ставит cookie refresh_token и отправляет приветственное письмо.",
+		RateLimited: true, Body: dto.RegisterInput{}, Status: http.StatusCreated, Data: authData{},
+		Errors: []*apierr.Error{apierr.InvalidJSON, apierr.ValidationFailed, apierr.EmailTaken}},
+	{Method: http.MethodPost, Path: "/login", Tag: "auth", Summary: "Вход по email и паролю",
+		Description: "Ставит cookie refresh_token.",
+		RateLimited: true, Body: dto.LoginInput{}, Data: authData{},
+		Errors: []*apierr.Error{apierr.InvalidJSON, apierr.ValidationFailed, apierr.InvalidCredentials}},
+	{Method: http.MethodPost, Path: "/refresh", Tag: "auth", Summary: "Новый access токен по refresh cookie",
+		RefreshAuth: true, RateLimited: true, Data: tokenData{},
+		Errors: []*apierr.Error{apierr.RefreshTokenRequired, apierr.InvalidRefreshToken}},
+	{Method: http.MethodPost, Path: "/logout", Tag: "auth", Summary: "Выход: удаляет refresh cookie", Data: Message{}},
+
+	// Пользователи
+	{Method: http.MethodGet, Path: "/avatars/:file", Tag: "users", Summary: "Сгенерированный аватар пользователя",
+		Description: "file — `{id}.png`. Поддерживает If-None-Match и отвечает 304.",
+		Query:       []Param{{Name: "size", Type: "integer", Description: "64, 128 или 512"}},
+		Produces:    "image/png",
+		Errors:      []*apierr.Error{apierr.AvatarNotFound, apierr.InvalidID, apierr.InvalidSize}},
+	{Method: http.MethodGet, Path: "/me", Tag: "users", Summary: "Текущий пользователь", Auth: true, RateLimited: true,
+		Data: userData{}, Errors: []*apierr.Error{apierr.UserNotFound}},
+	{Method: http.MethodGet, Path: "/me/security-log", Tag: "users", Summary: "Журнал событий своего аккаунта",
+		Description: "Входы, выходы, обновления токена и изменения профиля, в том числе администраторами и неудачные попытки входа. От новых к старым.",
+		Auth:        true, RateLimited: true, Data: securityLogData{},
+		Query: []Param{
+			{Name: "action", Description: "Одно или несколько действий через запятую: login, login_failed, logout, ..."},
+			{Name: "cursor", Description: "next_cursor из предыдущего ответа"},
+			{Name: "limit", Type: "integer", Description: "До 200, по умолчанию 50"},
+		},
+		Errors: []*apierr.Error{apierr.InvalidQuery, apierr.InvalidCursor}},
+	{Method: http.MethodPost, Path: "/me/export", Tag: "users", Summary: "Запросить выгрузку всех своих данных",
+		Description: "Ставит в очередь сборку ZIP-архива: профиль, аватар, загруженные файлы, журнал событий аккаунта и действующие сессии. Когда архив готов, на email приходит ссылка. Пока предыдущая выгрузка не собрана, возвращается она.",
+		Auth:        true, RateLimited: true, Status: http.StatusAccepted, Data: dataExportData{}},
+	{Method: http.MethodGet, Path: "/me/export", Tag: "users", Summary: "Статус последней выгрузки данных",
+		Description: "У готовой выгрузки — подписанная ссылка download_url, действует до expires_at. После expires_at архив удаляется, статус — expired.",
+		Auth:        true, RateLimited: true, Data: dataExportData{},
+		Errors: []*apierr.Error{apierr.ExportNotFound}},
+	{Method: http.MethodGet, Path: "/user/:id", Tag: "users", Summary: "Пользователь по ID", Auth: true, RateLimited: true,
+		Data: userData{}, Errors: []*apierr.Error{apierr.InvalidID, apierr.UserNotFound}},
+	{Method: http.MethodPost, Path: "/user", Tag: "users", Summary: "Создать пользователя", Auth: true, RateLimited: true,
+		Body: dto.CreateUserInput{}, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidJSON, apierr.ValidationFailed, apierr.EmailTaken}},
+	{Method: http.MethodPut, Path: "/user/:id", Tag: "users", Summary: "Изменить профиль (свой или любой для admin)",
+		Auth: true, RateLimited: true, Body: dto.UpdateUserInput{}, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.UserNotFound, apierr.EditForbidden, apierr.InvalidJSON, apierr.ValidationFailed, apierr.UnsupportedLanguage}},
+	{Method: http.MethodDelete, Path: "/user/:id", Tag: "users", Summary: "Удалить пользователя (мягко)",
+		Description: "Администратор может восстановить аккаунт в течение users.erasure_grace, после этого личные данные стираются безвозвратно.",
+		Auth:        true, RateLimited: true, Data: Message{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.UserNotFound, apierr.EditForbidden}},
+	{Method: http.MethodPost, Path: "/user/avatar", Tag: "users", Summary: "Загрузить аватар",
+		Description: "JPEG, PNG, GIF или WebP; сохраняются квадратные PNG 64, 128 и 512 px.",
+		Auth:        true, RateLimited: true, Multipart: []string{"avatar"}, Data: avatarData{},
+		Errors: []*apierr.Error{apierr.FileRequired, apierr.FileTooLarge, apierr.UserNotFound, apierr.FileUnreadable,
+			apierr.NotAnImage, apierr.ImageCorrupted, apierr.ImageTooLarge}},
+
+	// Администрирование
+	{Method: http.MethodGet, Path: "/admin/users", Tag: "admin", Summary: "Список пользователей", Auth: true, Admin: true, RateLimited: true,
+		Query: append([]Param{
+			{Name: "order_by", Description: "id, email, nickname или created_at"},
+			{Name: "desc", Type: "boolean"},
+			{Name: "search", Description: "Подстрока в nickname или email"},
+			{Name: "role"},
+			{Name: "email"},
+			{Name: "nickname"},
+		}, paging...),
+		Data: userListData{}},
+	{Method: http.MethodPut, Path: "/admin/user/:id/restore", Tag: "admin", Summary: "Восстановить удалённого пользователя",
+		Auth: true, Admin: true, RateLimited: true, Data: Message{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.UserNotFound, apierr.UserAlreadyActive, apierr.UserErased}},
+	{Method: http.MethodPut, Path: "/admin/user/:id/role", Tag: "admin", Summary: "Сменить роль пользователя",
+		Auth: true, Admin: true, RateLimited: true, Body: dto.UpdateRoleInput{}, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.CannotModerateSelf, apierr.UserNotFound, apierr.InvalidJSON, apierr.ValidationFailed, apierr.ModerationConflict}},
+	{Method: http.MethodPost, Path: "/admin/user/:id/suspend", Tag: "admin", Summary: "Временно заблокировать пользователя",
+		Description: "Без until — до снятия вручную. Уже выданные токены перестают работать сразу; повторный вызов заменяет причину и срок.",
+		Auth:        true, Admin: true, RateLimited: true, Body: dto.ModerationInput{}, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.CannotModerateSelf, apierr.UserNotFound, apierr.InvalidJSON, apierr.ValidationFailed, apierr.ModerationConflict}},
+	{Method: http.MethodPost, Path: "/admin/user/:id/ban", Tag: "admin", Summary: "Забанить пользователя",
+		Description: "Как suspend, но клиент получает account_banned. Без until — навсегда.",
+		Auth:        true, Admin: true, RateLimited: true, Body: dto.ModerationInput{}, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.CannotModerateSelf, apierr.UserNotFound, apierr.InvalidJSON, apierr.ValidationFailed, apierr.ModerationConflict}},
+	{Method: http.MethodPost, Path: "/admin/user/:id/reinstate", Tag: "admin", Summary: "Снять блокировку досрочно",
+		Auth: true, Admin: true, RateLimited: true, Data: userData{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.CannotModerateSelf, apierr.UserNotFound, apierr.UserNotBlocked, apierr.ModerationConflict}},
+	{Method: http.MethodPost, Path: "/admin/user/:id/purge", Tag: "admin", Summary: "Безвозвратно стереть личные данные пользователя",
+		Description: "Не дожидаясь users.erasure_grace после удаления; неудалённый аккаунт удаляется тем же действием. Email, ник, пароль, аватар и файлы без опубликованных ссылок стираются, в журнале аудита — IP, User-Agent и личные поля. Отменить нельзя.",
+		Auth:        true, Admin: true, RateLimited: true, Data: Message{},
+		Errors: []*apierr.Error{apierr.InvalidID, apierr.CannotModerateSelf, apierr.UserNotFound, apierr.UserErased}},
+	{Method: http.MethodGet, Path: "/admin/users/export", Tag: "admin", Summary: "Выгрузка пользователей в CSV",
+		Auth: true, Admin: true, RateLimited: true, Produces: "text/csv",
+		Query: []Param{{Name: "search"}, {Name: "role"}, {Name: "email"}, {Name: "nickname"}}},
+	{Method: http.MethodGet, Path: "/admin/audit-logs", Tag: "admin", Summary: "Журнал аудита",
+		Description: "Записи от новых к старым. Пустой next_cursor — страниц больше нет.",
+		Auth:        true, Admin: true, RateLimited: true, Data: auditLogsData{},
+		Query: append([]Param{
+			{Name: "cursor", Description: "next_cursor из предыдущего ответа"},
+			{Name: "limit", Type: "integer", Description: "До 200, по умолчанию 50"},
+		}, auditFilters...),
+		Errors: []*apierr.Error{apierr.InvalidQuery, apierr.InvalidCursor}},
+	{Method: http.MethodGet, Path: "/admin/audit-logs/export", Tag: "admin", Summary: "Выгрузка журнала аудита",
+		Description: "Все записи по фильтрам от старых к новым, с хешами цепочки. format=ndjson отдаёт application/x-ndjson.",
+		Auth:        true, Admin: true, RateLimited: true, Produces: "text/csv",
+		Query:  append([]Param{{Name: "format", Description: "csv (по умолчанию) или ndjson"}}, auditFilters...),
+		Errors: []*apierr.Error{apierr.InvalidQuery}},
+	{Method: http.MethodGet, Path: "/admin/audit-logs/verify", Tag: "admin", Summary: "Проверить цепочку хешей журнала",
+		Description: "Пересчитывает хеши всех записей. При разрыве valid=false, в report.break — первая сломанная запись.",
+		Auth:        true, Admin: true, RateLimited: true, Data: auditVerifyData{}},
+
+	// Медиатека
+	{Method: http.MethodGet, Path: "/media", Tag: "media", Summary: "Свои файлы и квота", Auth: true, RateLimited: true,
+		Query: append([]Param{
+			{Name: "search", Description: "Подстрока в имени файла"},
+			{Name: "type", Description: "Тип без подтипа: image, video, audio, application или text"},
+		}, paging...),
+		Data: mediaListData{}, Errors: []*apierr.Error{apierr.InvalidQuery}},
+	{Method: http.MethodPost, Path: "/media", Tag: "media", Summary: "Загрузить файл целиком", Auth: true, RateLimited: true,
+		Description: "Тип файла определяется по содержимому, расширение имени заменяется на расширение этого типа.",
+		Multipart:   []string{"file"}, Status: http.StatusCreated, Data: mediaData{},
+		Errors: []*apierr.Error{apierr.FileRequired, apierr.MediaTooLarge, apierr.MediaTypeNotAllowed, apierr.QuotaExceeded, apierr.FileUnreadable}},
+	{Method: http.MethodDelete, Path: "/media/:id", Tag: "media", Summary: "Удалить файл", Auth: true, RateLimited: true,
+		Data: Message{}, Errors: []*apierr.Error{apierr.InvalidID, apierr.MediaNotFound, apierr.MediaInUse}},
+	{Method: http.MethodPost, Path: "/media/uploads", Tag: "media", Summary: "Начать загрузку по частям", Auth: true, RateLimited: true,
+		Body: dto.CreateUploadInput{}, Status: http.StatusCreated, Data: createUploadData{},
+		Errors: []*apierr.Error{apierr.InvalidJSON, apierr.ValidationFailed, apierr.MediaTypeNotAllowed, apierr.UploadTooLarge, apierr.QuotaExceeded}},
+	{Method: http.MethodGet, Path: "/media/uploads/:id", Tag: "media", Summary: "Состояние загрузки (сколько получено)",
+		Auth: true, RateLimited: true, Data: uploadData{}, Errors: []*apierr.Error{apierr.UploadNotFound}},
+	{Method: http.MethodPatch, Path: "/media/uploads/:id", Tag: "media", Summary: "Отправить очередную часть",
+		Description: "Upload-Offset должен совпадать с received; после обрыва узнайте received через GET.",
+		Auth:        true, RateLimited: true, RawBody: "application/offset+octet-stream",
+		Headers: []Param{{Name: "Upload-Offset", Type: "integer", Required: true}},
+		Data:    uploadData{},
+		Errors: []*apierr.Error{apierr.UploadNotFound, apierr.InvalidUploadOffset, apierr.UploadOffsetMismatch, apierr.BodyUnreadable,
+			apierr.ChunkEmpty, apierr.ChunkTooLarge, apierr.UploadSizeExceeded, apierr.UploadConflict}},
+	{Method: http.MethodPost, Path: "/media/uploads/:id/complete", Tag: "media", Summary: "Собрать файл из частей",
+		Auth: true, RateLimited: true, Status: http.StatusCreated, Data: mediaData{},
+		Errors: []*apierr.Error{apierr.UploadNotFound, apierr.UploadIncomplete, apierr.MediaTypeNotAllowed}},
+	{Method: http.MethodDelete, Path: "/media/uploads/:id", Tag: "media", Summary: "Отменить загрузку",
+		Auth: true, RateLimited: true, Data: Message{}, Errors: []*apierr.Error{apierr.UploadNotFound}},
+
+	// Файлы локального хранилища (только storage.driver = local)
+	{Method: http.MethodGet, Path: "/uploads/*key", Tag: "files", Summary: "Публичные файлы: аватары, медиа",
+		Produces: "application/octet-stream",
+		Errors:   []*apierr.Error{apierr.FileNotFound}},
+	{Method: http.MethodGet, Path: "/files/*key", Tag: "files", Summary: "Приватный файл по подписанной ссылке",
+		Query:    []Param{{Name: "expires", Type: "integer", Required: true}, {Name: "signature", Required: true}},
+		Produces: "application/octet-stream",
+		Errors:   []*apierr.Error{apierr.FileNotFound, apierr.InvalidSignature, apierr.LinkExpired}},
+}
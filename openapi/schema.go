@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"encoding/json"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas строит JSON Schema по Go-типам: имена и обязательность полей берутся
+// из тегов json и validate, поэтому спецификация не расходится с dto.
+type schemas struct {
+	components map[string]any
+}
+
+func (s *schemas) of(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawJSONType:
+		return map[string]any{} // любой JSON
+	case t.Kind() == reflect.Pointer:
+		return nullable(s.of(t.Elem()))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		// анонимные и неэкспортируемые обёртки ответов описываются на месте
+		if !token.IsExported(t.Name()) {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			s.components[t.Name()] = nil // защита от рекурсии
+			s.components[t.Name()] = s.object(t)
+		}
+		return ref(t.Name())
+	}
+	return map[string]any{}
+}
+
+func (s *schemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.fields(t, properties, &required)
+
+	obj := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (s *schemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(f.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := s.of(f.Type)
+		if applyValidate(prop, f.Type, f.Tag.Get("validate")) {
+			*required = append(*required, name)
+		}
+		properties[name] = prop
+	}
+}
+
+// applyValidate переносит правила validator в схему и сообщает, обязательно ли поле.
+func applyValidate(prop map[string]any, t reflect.Type, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	isString := t.Kind() == reflect.String
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, numErr := strconv.ParseFloat(param, 64)
+		switch {
+		case name == "required":
+			required = true
+		case name == "email":
+			prop["format"] = "email"
+		case name == "url":
+			prop["format"] = "uri"
+		case name == "oneof":
+			prop["enum"] = strings.Fields(param)
+		case numErr != nil:
+		case isString && name == "min":
+			prop["minLength"] = n
+		case isString && name == "max":
+			prop["maxLength"] = n
+		case isString && name == "len":
+			prop["minLength"], prop["maxLength"] = n, n
+		case name == "min" || name == "gte":
+			prop["minimum"] = n
+		case name == "max" || name == "lte":
+			prop["maximum"] = n
+		case name == "gt":
+			prop["exclusiveMinimum"] = n
+		case name == "lt":
+			prop["exclusiveMaximum"] = n
+		}
+	}
+	return required
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func nullable(schema map[string]any) map[string]any {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	return map[string]any{"oneOf": []any{schema, map[string]any{"type": "null"}}}
+}
@@ -0,0 +1,408 @@
+package openapi
+
+import (
+	"Blog/apierr"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Operation описывает один маршрут. Path записывается как в gin (/user/:id),
+// в документе он превращается в /user/{id}.
+type Operation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	// Description — подробности, если Summary мало.
+	Description string
+
+	Auth        bool // нужен access токен
+	Admin       bool // нужна роль admin
+	RefreshAuth bool // нужен refresh токен в cookie
+	RateLimited bool
+
+	Query   []Param
+	Headers []Param
+
+	// Body — значение dto для JSON-тела; Multipart — имена полей с файлами;
+	// RawBody — content type тела, которое читается как есть.
+	Body      any
+	Multipart []string
+	RawBody   string
+
+	Status int // код успешного ответа, по умолчанию 200
+	// Data — то, что лежит в поле data успешного ответа. Если задан Produces,
+	// ответ не JSON-конверт, а содержимое этого типа.
+	Data     any
+	Produces string
+	// Plain — JSON-ответ без конверта success/data.
+	Plain any
+
+	Errors []*apierr.Error
+}
+
+type Param struct {
+	Name        string
+	Type        string // string, integer или boolean
+	Description string
+	Required    bool
+}
+
+// Message — data ответов, где есть только сообщение для пользователя.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// DocsPage — страница Swagger UI, которая загружает /openapi.json. Сам
+// интерфейс подгружается с CDN, в бинарник встроена только страница.
+//
+//go:embed docs.html
+var DocsPage []byte
+
+var (
+	once     sync.Once
+	document []byte
+)
+
+// JSON возвращает документ OpenAPI; собирается один раз.
+func JSON() []byte {
+	once.Do(func() {
+		var err error
+		document, err = json.MarshalIndent(Build(), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+	})
+	return document
+}
+
+// Build собирает документ OpenAPI 3.1 по списку Operations.
+func Build() map[string]any {
+	s := &schemas{components: map[string]any{}}
+	s.components["Error"] = errorSchema()
+	s.components["ErrorResponse"] = map[string]any{
+		"type":     "object",
+		"required": []string{"success", "error"},
+		"properties": map[string]any{
+			"success": map[string]any{"const": false},
+			"error":   ref("Error"),
+		},
+	}
+
+	paths := map[string]map[string]any{}
+	for _, op := range Operations {
+		path := specPath(op.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(op.Method)] = s.operation(op)
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Blog API",
+			"version":     "1.0.0",
+			"description": "Ответы оборачиваются в `{\"success\": true, \"data\": ...}`, ошибки — в `{\"success\": false, \"error\": {...}}`. Коды ошибок описаны в docs/errors.md. Язык сообщений выбирается через `?lang=`, язык профиля или `Accept-Language`.",
+		},
+		"tags":  tags(),
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "Access токен из /login, /register или /refresh.",
+				},
+				"refreshCookie": map[string]any{
+					"type":        "apiKey",
+					"in":          "cookie",
+					"name":        "refresh_token",
+					"description": "HttpOnly cookie, которую ставят /login и /register.",
+				},
+			},
+		},
+	}
+}
+
+func (s *schemas) operation(op Operation) map[string]any {
+	result := map[string]any{
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+	if op.Description != "" {
+		result["description"] = op.Description
+	}
+
+	var params []any
+	for _, name := range pathParams(op.Path) {
+		params = append(params, map[string]any{
+			"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		params = append(params, param(p, "query"))
+	}
+	for _, p := range op.Headers {
+		params = append(params, param(p, "header"))
+	}
+	if len(params) > 0 {
+		result["parameters"] = params
+	}
+
+	switch {
+	case op.Body != nil:
+		result["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.of(reflect.TypeOf(op.Body))}},
+		}
+	case len(op.Multipart) > 0:
+		props := map[string]any{}
+		for _, field := range op.Multipart {
+			props[field] = map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}
+		}
+		result["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{"multipart/form-data": map[string]any{
+				"schema": map[string]any{"type": "object", "properties": props, "required": op.Multipart},
+			}},
+		}
+	case op.RawBody != "":
+		result["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{op.RawBody: map[string]any{"schema": map[string]any{"type": "string", "contentMediaType": op.RawBody}}},
+		}
+	}
+
+	switch {
+	case op.Auth && op.RefreshAuth:
+		result["security"] = []any{map[string]any{"bearerAuth": []string{}, "refreshCookie": []string{}}}
+	case op.Auth:
+		result["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+	case op.RefreshAuth:
+		result["security"] = []any{map[string]any{"refreshCookie": []string{}}}
+	default:
+		result["security"] = []any{}
+	}
+
+	result["responses"] = s.responses(op)
+	return result
+}
+
+func (s *schemas) responses(op Operation) map[string]any {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	var content map[string]any
+	switch {
+	case op.Produces != "":
+		content = map[string]any{op.Produces: map[string]any{"schema": map[string]any{"type": "string"}}}
+	case op.Plain != nil:
+		content = map[string]any{"application/json": map[string]any{"schema": s.of(reflect.TypeOf(op.Plain))}}
+	default:
+		data := map[string]any{}
+		if op.Data != nil {
+			data = s.of(reflect.TypeOf(op.Data))
+		}
+		content = map[string]any{"application/json": map[string]any{"schema": map[string]any{
+			"type":     "object",
+			"required": []string{"success", "data"},
+			"properties": map[string]any{
+				"success": map[string]any{"const": true},
+				"data":    data,
+			},
+		}}}
+	}
+
+	responses := map[string]any{
+		strconv.Itoa(status): map[string]any{"description": http.StatusText(status), "content": content},
+	}
+	if op.RateLimited {
+		responses[strconv.Itoa(status)].(map[string]any)["headers"] = rateLimitHeaders()
+	}
+
+	// ошибки группируются по статусу, в описании перечислены возможные коды
+	byStatus := map[int][]string{}
+	for _, e := range operationErrors(op) {
+		if !slices.Contains(byStatus[e.Status], e.Code) {
+			byStatus[e.Status] = append(byStatus[e.Status], e.Code)
+		}
+	}
+	for code, names := range byStatus {
+		resp := map[string]any{
+			"description": "Коды: `" + strings.Join(names, "`, `") + "`",
+			"content":     map[string]any{"application/json": map[string]any{"schema": ref("ErrorResponse")}},
+		}
+		if code == http.StatusTooManyRequests {
+			headers := rateLimitHeaders()
+			headers["Retry-After"] = map[string]any{"description": "Через сколько секунд можно повторить", "schema": map[string]any{"type": "integer"}}
+			resp["headers"] = headers
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	return responses
+}
+
+// operationErrors дополняет ошибки маршрута общими: авторизация, лимиты, 500.
+func operationErrors(op Operation) []*apierr.Error {
+	errs := append([]*apierr.Error(nil), op.Errors...)
+	if op.Auth {
+		errs = append(errs, apierr.TokenRequired, apierr.InvalidToken, apierr.AccountNotFound,
+			apierr.AccountSuspended, apierr.AccountBanned)
+	}
+	if op.Admin {
+		errs = append(errs, apierr.AdminRequired)
+	}
+	if op.RateLimited {
+		errs = append(errs, apierr.RateLimited)
+	}
+	return append(errs, apierr.Internal)
+}
+
+func errorSchema() map[string]any {
+	var codes []string
+	for _, e := range apierr.Catalog() {
+		codes = append(codes, e.Code)
+	}
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"code", "message", "request_id"},
+		"properties": map[string]any{
+			"code":       map[string]any{"type": "string", "enum": codes, "description": "Стабильный код ошибки, см. docs/errors.md"},
+			"message":    map[string]any{"type": "string", "description": "Текст на языке запроса"},
+			"fields":     map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}, "description": "Ошибки по полям (validation_failed)"},
+			"details":    map[string]any{"type": "object", "description": "Дополнительные данные, например received для upload_offset_mismatch"},
+			"request_id": map[string]any{"type": "string", "description": "Совпадает с заголовком X-Request-ID"},
+		},
+	}
+}
+
+func rateLimitHeaders() map[string]any {
+	integer := map[string]any{"type": "integer"}
+	return map[string]any{
+		"RateLimit-Limit":     map[string]any{"description": "Размер ведра", "schema": integer},
+		"RateLimit-Remaining": map[string]any{"description": "Сколько запросов осталось", "schema": integer},
+		"RateLimit-Reset":     map[string]any{"description": "Через сколько секунд ведро наполнится", "schema": integer},
+	}
+}
+
+func param(p Param, in string) map[string]any {
+	t := p.Type
+	if t == "" {
+		t = "string"
+	}
+	result := map[string]any{"name": p.Name, "in": in, "schema": map[string]any{"type": t}}
+	if p.Description != "" {
+		result["description"] = p.Description
+	}
+	if p.Required {
+		result["required"] = true
+	}
+	return result
+}
+
+func tags() []any {
+	var result []any
+	var seen []string
+	for _, op := range Operations {
+		if !slices.Contains(seen, op.Tag) {
+			seen = append(seen, op.Tag)
+			result = append(result, map[string]any{"name": op.Tag})
+		}
+	}
+	return result
+}
+
+func specPath(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func pathParams(ginPath string) []string {
+	var names []string
+	for _, p := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			names = append(names, p[1:])
+		}
+	}
+	return names
+}
+
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, r := range specPath(op.Path) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+// Check сверяет маршруты gin с документом: возвращает маршруты без описания
+// и описания без маршрута. HEAD для раздачи файлов считаются описанными
+// вместе с GET.
+func Check(routes gin.RoutesInfo) (undocumented, stale []string) {
+	documented := map[string]bool{}
+	for _, op := range Operations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	registered := map[string]bool{}
+	for _, r := range routes {
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		if r.Method == http.MethodHead && documented[http.MethodGet+" "+r.Path] {
+			continue
+		}
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	return undocumented, stale
+}
+
+// CheckError — Check в виде одной ошибки для CLI.
+func CheckError(routes gin.RoutesInfo) error {
+	undocumented, stale := Check(routes)
+	if len(undocumented) == 0 && len(stale) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, r := range undocumented {
+		fmt.Fprintf(&b, "\n  нет в спецификации: %s", r)
+	}
+	for _, r := range stale {
+		fmt.Fprintf(&b, "\n  описан, но не зарегистрирован: %s", r)
+	}
+	return fmt.Errorf("спецификация OpenAPI расходится с маршрутами:%s", b.String())
+}
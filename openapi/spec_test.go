@@ -0,0 +1,33 @@
+package openapi_test
+
+import (
+	"Blog/config"
+	"Blog/filestore"
+	"Blog/openapi"
+	"Blog/ratelimit"
+	"Blog/routes"
+	"github.com/gin-gonic/gin"
+	"testing"
+)
+
+// TestRoutesDocumented — то же, что `blog openapi check`: каждый маршрут
+// роутера описан в Operations, и у каждого описания есть маршрут.
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// маршруты файлов регистрируются только для локального хранилища
+	filestore.Init(config.Storage{Driver: "local", LocalDir: t.TempDir(), SigningKey: "0123456789abcdef"})
+
+	cfg := config.Default()
+	r, err := routes.NewRouter(&cfg, ratelimit.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	undocumented, stale := openapi.Check(r.Routes())
+	for _, route := range undocumented {
+		t.Errorf("маршрут %s не описан в openapi.Operations", route)
+	}
+	for _, op := range stale {
+		t.Errorf("описание %s есть, а маршрута нет", op)
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Memory хранит ведра в памяти процесса; подходит для одного экземпляра.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(limit, b.tokens, allowed), nil
+}
+
+// sweep раз в минуту выбрасывает ведра, к которым давно не обращались,
+// чтобы карта не росла бесконечно от случайных IP.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > time.Hour {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRefill(t *testing.T) {
+	m := NewMemory()
+	limit := Limit{Rate: 2, Burst: 3} // 2 токена в секунду
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		res, _ := m.Allow(ctx, "ip:1", limit)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("запрос %d: %+v", i+1, res)
+		}
+	}
+	res, _ := m.Allow(ctx, "ip:1", limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Fatalf("ведро пусто, а получили %+v", res)
+	}
+
+	// другой ключ — своё ведро
+	if res, _ := m.Allow(ctx, "ip:2", limit); !res.Allowed {
+		t.Fatalf("ip:2: %+v", res)
+	}
+
+	// через полсекунды появляется один токен
+	m.buckets["ip:1"].updated = m.buckets["ip:1"].updated.Add(-500 * time.Millisecond)
+	if res, _ := m.Allow(ctx, "ip:1", limit); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("после пополнения: %+v", res)
+	}
+
+	// за долгий простой ведро наполняется не больше чем до Burst
+	m.buckets["ip:1"].updated = m.buckets["ip:1"].updated.Add(-time.Minute)
+	res, _ = m.Allow(ctx, "ip:1", limit)
+	if !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("после простоя: %+v", res)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	limit := Limit{Rate: 1, Burst: 1}
+	m.Allow(context.Background(), "old", limit)
+	m.buckets["old"].updated = time.Now().Add(-2 * time.Hour)
+	m.lastSweep = time.Now().Add(-2 * time.Minute)
+
+	m.Allow(context.Background(), "new", limit)
+	if _, ok := m.buckets["old"]; ok {
+		t.Error("давно не использованное ведро не удалено")
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Postgres хранит ведра в таблице rate_limit_buckets, поэтому лимиты общие
+// для всех экземпляров. Пополнение и списание делаются одним UPSERT-ом.
+type Postgres struct {
+	DB *gorm.DB
+}
+
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{DB: db}
+}
+
+const allowSQL = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (@key, @burst - 1, TRUE, clock_timestamp())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE
+        WHEN LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate) >= 1
+        THEN LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate) - 1
+        ELSE LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate)
+    END,
+    allowed = LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * @rate) >= 1,
+    updated_at = clock_timestamp()
+RETURNING tokens, allowed`
+
+func (p *Postgres) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := p.DB.WithContext(ctx).Raw(allowSQL, map[string]interface{}{
+		"key":   key,
+		"burst": float64(limit.Burst),
+		"rate":  limit.Rate,
+	}).Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, row.Tokens, row.Allowed), nil
+}
+
+// Cleanup удаляет ведра, к которым не обращались дольше olderThan.
+func (p *Postgres) Cleanup(ctx context.Context, olderThan time.Duration) error {
+	return p.DB.WithContext(ctx).
+		Exec("DELETE FROM rate_limit_buckets WHERE updated_at < ?", time.Now().Add(-olderThan)).Error
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit — параметры token bucket: Burst токенов в ведре, пополнение Rate токенов в секунду.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter — через сколько появится следующий токен (0, если запрос разрешён).
+	RetryAfter time.Duration
+	// Reset — через сколько ведро снова будет полным.
+	Reset time.Duration
+}
+
+// Backend хранит состояние ведер. Allow атомарно пополняет ведро по прошедшему
+// времени и, если есть токен, забирает его.
+type Backend interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result собирает Result по числу токенов после попытки.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(s)) * time.Second
+}
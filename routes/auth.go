@@ -2,12 +2,13 @@ package routes
 
 import (
 	"Blog/handlers"
+	"Blog/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(r *gin.Engine) {
-	r.POST("/login", handlers.Login)
-	r.POST("/register", handlers.Register)
-	r.POST("/refresh", handlers.RefreshToken)
-	r.POST("/logout", handlers.Logout)
+func AuthRoutes(r *gin.Engine, auth *handlers.Auth, limiter *middleware.RateLimiter) {
+	r.POST("/login", limiter.Limit("login"), auth.Login)
+	r.POST("/register", limiter.Limit("register"), auth.Register)
+	r.POST("/refresh", limiter.Limit("refresh"), auth.RefreshToken)
+	r.POST("/logout", auth.Logout)
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"Blog/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func DocsRoutes(r *gin.Engine) {
+	r.GET("/openapi.json", handlers.OpenAPISpec)
+	r.GET("/docs", handlers.APIDocs)
+}
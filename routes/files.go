@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"Blog/filestore"
+	"Blog/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func FileRoutes(r *gin.Engine) {
+	local, ok := filestore.LocalStore()
+	if !ok {
+		// файлы S3 раздаются самим хранилищем
+		return
+	}
+
+	r.GET(local.BaseURL+"/*key", handlers.ServePublicFile)
+	r.HEAD(local.BaseURL+"/*key", handlers.ServePublicFile)
+	r.GET(filestore.SignedPathPrefix+"*key", handlers.ServeSignedFile)
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"Blog/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(r *gin.Engine) {
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+}
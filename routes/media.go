@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"Blog/handlers"
+	"Blog/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MediaRoutes(r *gin.Engine, requireAuth gin.HandlerFunc, limiter *middleware.RateLimiter, uploads *handlers.Uploads) {
+	media := r.Group("/media")
+	media.Use(requireAuth, limiter.Limit("api"))
+
+	media.GET("", uploads.ListMedia)
+	media.POST("", limiter.Limit("upload"), uploads.UploadMedia)
+	media.DELETE("/:id", handlers.DeleteMedia)
+
+	media.POST("/uploads", limiter.Limit("upload"), uploads.CreateMediaUpload)
+	media.GET("/uploads/:id", handlers.GetMediaUpload)
+	media.PATCH("/uploads/:id", uploads.UploadMediaChunk)
+	media.POST("/uploads/:id/complete", handlers.CompleteMediaUpload)
+	media.DELETE("/uploads/:id", handlers.AbortMediaUpload)
+}
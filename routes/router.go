@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"Blog/config"
+	"Blog/handlers"
+	"Blog/middleware"
+	"Blog/ratelimit"
+	"Blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter собирает gin со всеми middleware и маршрутами. Настройки
+// обработчиков и middleware берутся из cfg, ведра лимитов запросов — из
+// rateBackend. База для этого не нужна, поэтому роутер строит и
+// `blog openapi check`.
+func NewRouter(cfg *config.Config, rateBackend ratelimit.Backend) (*gin.Engine, error) {
+	r := gin.New()
+	// без списка gin доверяет X-Forwarded-For от кого угодно, и клиент мог бы
+	// подставить чужой IP в обход лимитов
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+	// логгер запроса лежит в c.Request.Context(); с fallback его видит и
+	// storage.DB.WithContext(c)
+	r.ContextWithFallback = true
+	r.HandleMethodNotAllowed = true
+	r.Use(middleware.Tracing(), middleware.RequestID(), middleware.Locale(), middleware.AccessLog(), middleware.Metrics(), middleware.Errors(), middleware.Recover())
+	r.NoRoute(middleware.NoRoute)
+	r.NoMethod(middleware.NoMethod)
+	HealthRoutes(r)
+	MetricsRoutes(r)
+	DocsRoutes(r)
+	FileRoutes(r)
+
+	tokens := utils.NewTokens(cfg.JWT)
+	limiter := middleware.NewRateLimiter(cfg.RateLimit, rateBackend)
+	requireAuth := middleware.RequireAuth(tokens)
+	uploads := handlers.NewUploads(cfg.Limits)
+
+	RegisterUserRoutes(r, requireAuth, limiter, uploads)
+	AuthRoutes(r, handlers.NewAuth(tokens), limiter)
+	MediaRoutes(r, requireAuth, limiter, uploads)
+	return r, nil
+}
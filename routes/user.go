@@ -6,22 +6,34 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterUserRoutes(r *gin.Engine) {
+func RegisterUserRoutes(r *gin.Engine, requireAuth gin.HandlerFunc, limiter *middleware.RateLimiter, uploads *handlers.Uploads) {
+	r.GET("/avatars/:file", handlers.GetDefaultAvatar)
+
 	protected := r.Group("/")
-	protected.Use(middleware.RequireAuth())
+	protected.Use(requireAuth, limiter.Limit("api"))
 
 	protected.GET("/user/:id", handlers.GetUser)
 	protected.POST("/user", handlers.CreateUser)
 	protected.GET("/me", handlers.GetCurrentUser)
-	protected.POST("user/avatar", handlers.UploadAvatar)
+	protected.GET("/me/security-log", handlers.GetSecurityLog)
+	protected.POST("/me/export", handlers.RequestDataExport)
+	protected.GET("/me/export", handlers.GetDataExport)
+	protected.POST("user/avatar", limiter.Limit("upload"), uploads.UploadAvatar)
 	protected.PUT("/user/:id", middleware.CanEditOrAdmin(), handlers.UpdateUser)
 	protected.DELETE("/user/:id", middleware.CanEditOrAdmin(), handlers.DeleteUser)
 
 	adminRoutes := r.Group("/admin")
-	adminRoutes.Use(middleware.RequireAuth(), middleware.RequireAdmin())
+	adminRoutes.Use(requireAuth, limiter.Limit("api"), middleware.RequireAdmin())
 	adminRoutes.GET("/users", handlers.GetUsers)
 	adminRoutes.PUT("/user/:id/restore", handlers.RestoreUser)
+	adminRoutes.PUT("/user/:id/role", handlers.ChangeUserRole)
+	adminRoutes.POST("/user/:id/suspend", handlers.SuspendUser)
+	adminRoutes.POST("/user/:id/ban", handlers.BanUser)
+	adminRoutes.POST("/user/:id/reinstate", handlers.ReinstateUser)
+	adminRoutes.POST("/user/:id/purge", handlers.PurgeUser)
 	adminRoutes.GET("/users/export",handlers.ExportUsersCSV)
 	adminRoutes.GET("/audit-logs", handlers.GetAuditLogs)
+	adminRoutes.GET("/audit-logs/verify", handlers.VerifyAuditLogs)
+	adminRoutes.GET("/audit-logs/export", handlers.ExportAuditLogs)
 
 }
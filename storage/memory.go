@@ -1,20 +1,71 @@
 package storage
 
 import (
+	"Blog/config"
+	"Blog/logging"
+	"Blog/tracing"
+	"context"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"log/slog"
+	"time"
 )
 
 var DB *gorm.DB
 
-func ConnectDB() {
-	dsn := "host=localhost user=postgres password=root dbname=blogdb port=5432 sslmode=disable TimeZone=Asia/Almaty"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ConnectDB подключается к базе, повторяя попытки с растущей паузой, пока не
+// истечёт cfg.ConnectTimeout: при старте в контейнерах Postgres часто ещё не готов.
+func ConnectDB(ctx context.Context, cfg config.Database) error {
+	deadline := time.Now().Add(cfg.ConnectTimeout.Duration)
+	backoff := 500 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{
+			Logger: logging.NewGormLogger(cfg.SlowQueryThreshold.Duration),
+		})
+		if err == nil {
+			if err := db.Use(tracing.GormPlugin{}); err != nil {
+				return err
+			}
+			DB = db
+			slog.Info("Connected to database")
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("failed to connect database after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("Database not ready, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
+
+// Ping проверяет, что база отвечает.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not connected")
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		panic("failed to connect database")
+		return err
 	}
+	return sqlDB.PingContext(ctx)
+}
 
-	DB = db
-	fmt.Println("✅ Connected to database")
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
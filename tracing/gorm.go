@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "otel:span"
+
+// GormPlugin создаёт по спану на каждый запрос GORM. Родителем становится
+// спан из контекста, переданного через DB.WithContext.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "otel-tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("otel:before_create", startSpan("db.create")),
+		cb.Create().After("gorm:create").Register("otel:after_create", endSpan),
+		cb.Query().Before("gorm:query").Register("otel:before_query", startSpan("db.query")),
+		cb.Query().After("gorm:query").Register("otel:after_query", endSpan),
+		cb.Update().Before("gorm:update").Register("otel:before_update", startSpan("db.update")),
+		cb.Update().After("gorm:update").Register("otel:after_update", endSpan),
+		cb.Delete().Before("gorm:delete").Register("otel:before_delete", startSpan("db.delete")),
+		cb.Delete().After("gorm:delete").Register("otel:after_delete", endSpan),
+		cb.Row().Before("gorm:row").Register("otel:before_row", startSpan("db.row")),
+		cb.Row().After("gorm:row").Register("otel:after_row", endSpan),
+		cb.Raw().Before("gorm:raw").Register("otel:before_raw", startSpan("db.raw")),
+		cb.Raw().After("gorm:raw").Register("otel:after_raw", endSpan),
+	)
+}
+
+func startSpan(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := Tracer().Start(db.Statement.Context, name, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"Blog/apierr"
+	"Blog/models"
+	"time"
+)
+
+// AccountBlocked возвращает ошибку для заблокированного аккаунта или nil.
+// Причина и срок уходят клиенту в details, чтобы показать их пользователю.
+func AccountBlocked(u models.User) error {
+	if !u.Blocked(time.Now()) {
+		return nil
+	}
+	e := apierr.AccountSuspended
+	if u.Status == models.StatusBanned {
+		e = apierr.AccountBanned
+	}
+	e = e.WithDetail("reason", u.StatusReason)
+	if u.StatusUntil != nil {
+		e = e.WithDetail("until", u.StatusUntil.UTC())
+	}
+	return e
+}
@@ -1,31 +1,58 @@
 package utils
 
 import (
+	"Blog/audit"
+	"Blog/metrics"
 	"Blog/models"
-	"Blog/storage"
+	"context"
 	"github.com/gin-gonic/gin"
-	"log"
-	"time"
+	"gorm.io/gorm"
 )
 
-func LogAudit(c *gin.Context, action, object string, objectID uint, metadata string) {
+// LogAudit записывает действие из HTTP-запроса в транзакции tx, в которой
+// сделано само изменение. Ошибку нужно вернуть из транзакции, чтобы
+// изменение без записи в журнале не сохранилось.
+func LogAudit(c *gin.Context, tx *gorm.DB, action, object string, objectID uint, metadata audit.Metadata) error {
+	return WriteAudit(c, tx, AuditEntry(c, action, object, objectID, metadata))
+}
 
-	userID := c.GetUint("user_id")
-	ip := c.ClientIP()
-	ua := c.GetHeader("User-Agent")
+// AuditEntry собирает запись из HTTP-запроса: автор — пользователь из
+// токена, IP и User-Agent — из запроса. Для входа и регистрации, где токена
+// ещё нет, UserID заполняется вызывающим.
+func AuditEntry(c *gin.Context, action, object string, objectID uint, metadata audit.Metadata) models.AuditLog {
+	metadata.RequestID = c.GetString("request_id")
 
-	logEntry := models.AuditLog{
-		UserID:    userID,
+	return models.AuditLog{
+		UserID:    c.GetUint("user_id"),
 		Action:    action,
 		Object:    object,
 		ObjectID:  objectID,
-		Timestamp: time.Now(),
-		IP:        ip,
-		UserAgent: ua,
-		Metadata:  metadata,
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Metadata:  metadata.JSON(),
 	}
+}
+
+// SystemAuditEntry собирает запись действия консольной команды или фоновой
+// задачи; source — "cli" или "job".
+func SystemAuditEntry(source, action, object string, objectID uint, metadata audit.Metadata) models.AuditLog {
+	metadata.Source = source
+
+	return models.AuditLog{
+		Action:    action,
+		Object:    object,
+		ObjectID:  objectID,
+		UserAgent: source,
+		Metadata:  metadata.JSON(),
+	}
+}
 
-	if err := storage.DB.Create(&logEntry).Error; err != nil {
-		log.Println("Ошибка при записи в аудит лог:", err)
+// WriteAudit записывает готовую запись; используется и там, где нет
+// HTTP-запроса (CLI, фоновые задачи).
+func WriteAudit(ctx context.Context, tx *gorm.DB, logEntry models.AuditLog) error {
+	if err := audit.Record(ctx, tx, &logEntry); err != nil {
+		metrics.AuditWriteFailures.Inc()
+		return err
 	}
+	return nil
 }
@@ -1,13 +1,23 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"Blog/tracing"
+	"context"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.hash")
+	defer span.End()
 
-func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
 }
 
-func CheckPasswordHash(password, hash string) bool {
+func CheckPasswordHash(ctx context.Context, password, hash string) bool {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.compare")
+	defer span.End()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+const identiconGrid = 5
+
+// Identicon рисует симметричный узор 5x5 по хешу seed. Один и тот же seed
+// всегда даёт одну и ту же картинку.
+func Identicon(seed []byte, size int) image.Image {
+	sum := sha256.Sum256(seed)
+	fg := identiconColor(sum[0], sum[1])
+	bg := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+
+	padding := size / 10
+	cell := (size - 2*padding) / identiconGrid
+	offset := (size - cell*identiconGrid) / 2
+
+	bit := 0
+	for x := 0; x < (identiconGrid+1)/2; x++ {
+		for y := 0; y < identiconGrid; y++ {
+			filled := sum[2+bit/8]>>(bit%8)&1 == 1
+			bit++
+			if !filled {
+				continue
+			}
+			for _, col := range []int{x, identiconGrid - 1 - x} {
+				r := image.Rect(offset+col*cell, offset+y*cell, offset+(col+1)*cell, offset+(y+1)*cell)
+				draw.Draw(img, r, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// identiconColor выбирает насыщенный цвет по оттенку из двух байт хеша.
+func identiconColor(a, b byte) color.RGBA {
+	hue := (int(a)<<8 | int(b)) % 360
+	const s, l = 0.55, 0.5
+
+	c := (1 - abs(2*l-1)) * s
+	h := float64(hue) / 60
+	x := c * (1 - abs(mod2(h)-1))
+	var r, g, bl float64
+	switch {
+	case h < 1:
+		r, g = c, x
+	case h < 2:
+		r, g = x, c
+	case h < 3:
+		g, bl = c, x
+	case h < 4:
+		g, bl = x, c
+	case h < 5:
+		r, bl = x, c
+	default:
+		r, bl = c, x
+	}
+	m := l - c/2
+	return color.RGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((bl + m) * 255), A: 255}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func mod2(f float64) float64 {
+	return f - 2*float64(int(f/2))
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	maxImageDimension = 4096
+	// декодированное изображение занимает 4 байта на пиксель: 12 Мп — 48 МБ
+	maxImagePixels      = 12_000_000
+	AvatarContentType   = "image/png"
+	avatarFileExtension = ".png"
+)
+
+var AvatarSizes = []int{64, 128, 512}
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var (
+	ErrNotAnImage     = errors.New("файл не является изображением")
+	ErrImageTooLarge  = errors.New("слишком большое изображение")
+	ErrImageCorrupted = errors.New("не удалось прочитать изображение")
+)
+
+// DecodeImage определяет настоящий тип файла по содержимому и декодирует его.
+// Перекодирование отбрасывает EXIF и прочие метаданные исходного файла.
+func DecodeImage(data []byte) (image.Image, error) {
+	mtype := mimetype.Detect(data)
+	if !allowedImageTypes[mtype.String()] {
+		return nil, ErrNotAnImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImageCorrupted
+	}
+	if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImageCorrupted
+	}
+	return img, nil
+}
+
+// SquareThumbnail вырезает центральный квадрат и масштабирует его до size x size.
+func SquareThumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+	return dst
+}
+
+func EncodePNG(w io.Writer, img image.Image) error {
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	return enc.Encode(w, img)
+}
+
+// AvatarFileName возвращает имя файла варианта аватара заданного размера.
+func AvatarFileName(size int) string {
+	return fmt.Sprintf("%d%s", size, avatarFileExtension)
+}
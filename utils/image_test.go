@@ -0,0 +1,169 @@
+package utils_test
+
+import (
+	"Blog/dto"
+	"Blog/utils"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"maps"
+	"testing"
+)
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader — PNG только с заголовком IHDR: размеры читаются без
+// декодирования, большое изображение не нужно создавать целиком.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 0, 17)
+	ihdr = append(ihdr, "IHDR"...)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8 бит, RGBA
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestDecodeImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	pngData := encodeImage(t, "png", img)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"png", pngData, nil},
+		{"jpeg", encodeImage(t, "jpeg", img), nil},
+		{"gif", encodeImage(t, "gif", img), nil},
+		// тип определяется по содержимому, а не по тому, что заявил клиент
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="4" height="3"></svg>`), utils.ErrNotAnImage},
+		{"html", []byte("<!DOCTYPE html><img src=x>"), utils.ErrNotAnImage},
+		{"текст", []byte("hello"), utils.ErrNotAnImage},
+		{"обрезанный png", pngData[:len(pngData)/2], utils.ErrImageCorrupted},
+		// размер проходит, дальше декодер не находит данных
+		{"сторона на пределе", pngHeader(4096, 1), utils.ErrImageCorrupted},
+		{"широкое", pngHeader(4097, 1), utils.ErrImageTooLarge},
+		{"высокое", pngHeader(1, 4097), utils.ErrImageTooLarge},
+		{"слишком много пикселей", pngHeader(4000, 3001), utils.ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utils.DecodeImage(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ошибка %v, want %v", err, tt.err)
+			}
+			if err == nil && got.Bounds().Size() != image.Pt(4, 3) {
+				t.Errorf("размер %v, want 4x3", got.Bounds().Size())
+			}
+		})
+	}
+}
+
+func TestSquareThumbnail(t *testing.T) {
+	// левая треть красная, середина зелёная, правая треть синяя:
+	// в квадрат из центра попадает только зелёная часть
+	src := image.NewRGBA(image.Rect(10, 20, 310, 120))
+	for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+		c := color.RGBA{G: 255, A: 255}
+		switch {
+		case x < 110:
+			c = color.RGBA{R: 255, A: 255}
+		case x >= 210:
+			c = color.RGBA{B: 255, A: 255}
+		}
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			src.Set(x, y, c)
+		}
+	}
+
+	tests := []struct {
+		name string
+		src  image.Image
+		size int
+	}{
+		{"уменьшение", src, 64},
+		{"увеличение", src, 512},
+		{"высокое", image.NewRGBA(image.Rect(0, 0, 10, 30)), 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := utils.SquareThumbnail(tt.src, tt.size)
+			if b := dst.Bounds(); b != image.Rect(0, 0, tt.size, tt.size) {
+				t.Fatalf("границы %v, want %dx%d от нуля", b, tt.size, tt.size)
+			}
+			if tt.src != src {
+				return
+			}
+			for _, p := range []image.Point{{0, 0}, {tt.size - 1, tt.size / 2}, {tt.size / 2, tt.size - 1}} {
+				r, g, b, _ := dst.At(p.X, p.Y).RGBA()
+				if r > 0x1000 || b > 0x1000 || g < 0xf000 {
+					t.Errorf("пиксель %v = (%x, %x, %x), want зелёный", p, r, g, b)
+				}
+			}
+		})
+	}
+}
+
+func TestAvatarURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want map[string]string
+	}{
+		{"пусто", "", nil},
+		{
+			"аватар",
+			"/uploads/avatars/7/abc/512.png",
+			map[string]string{
+				"64":  "/uploads/avatars/7/abc/64.png",
+				"128": "/uploads/avatars/7/abc/128.png",
+				"512": "/uploads/avatars/7/abc/512.png",
+			},
+		},
+		{
+			"внешнее хранилище",
+			"https://cdn.example.com/avatars/7/abc/512.png",
+			map[string]string{
+				"64":  "https://cdn.example.com/avatars/7/abc/64.png",
+				"128": "https://cdn.example.com/avatars/7/abc/128.png",
+				"512": "https://cdn.example.com/avatars/7/abc/512.png",
+			},
+		},
+		// старые аватары — один файл без вариантов
+		{"старый аватар", "/uploads/avatars/7/photo.jpg", nil},
+		{"похожее имя", "/uploads/avatars/7/abc/x512.png", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dto.AvatarURLs(tt.url); !maps.Equal(got, tt.want) {
+				t.Errorf("AvatarURLs(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,28 +1,48 @@
 package utils
 
 import (
+	"Blog/config"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"time"
 )
 
-var jwtSecret = []byte("super-secret-key")
+// Tokens выпускает и проверяет JWT с секретом и сроками из конфигурации.
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokens(cfg config.JWT) *Tokens {
+	return &Tokens{
+		secret:     []byte(cfg.Secret),
+		accessTTL:  cfg.AccessTTL.Duration,
+		refreshTTL: cfg.RefreshTTL.Duration,
+	}
+}
+
+func (t *Tokens) RefreshTTL() time.Duration {
+	return t.refreshTTL
+}
+
+func (t *Tokens) key(*jwt.Token) (interface{}, error) {
+	return t.secret, nil
+}
 
-func GenerateJWT(userID uint) (string, error) {
+func (t *Tokens) GenerateJWT(userID uint) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+		"exp":     time.Now().Add(t.accessTTL).Unix(),
 		"type":    "access",
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(t.secret)
 }
 
-func ParseAccessToken(tokenStr string) (uint, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func (t *Tokens) ParseAccessToken(tokenStr string) (uint, error) {
+	token, err := jwt.Parse(tokenStr, t.key)
 
 	if err != nil || !token.Valid {
 		return 0, errors.New("invalid token")
@@ -41,10 +61,8 @@ func ParseAccessToken(tokenStr string) (uint, error) {
 	return uint(idFloat), nil
 }
 
-func ParseJWT(tokenStr string) (uint, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func (t *Tokens) ParseJWT(tokenStr string) (uint, error) {
+	token, err := jwt.Parse(tokenStr, t.key)
 
 	if err != nil || !token.Valid {
 		return 0, err
@@ -59,21 +77,19 @@ func ParseJWT(tokenStr string) (uint, error) {
 	return 0, jwt.ErrInvalidKey
 }
 
-func GenerateRefreshToken(userID uint) (string, error) {
+func (t *Tokens) GenerateRefreshToken(userID uint) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"exp":     time.Now().Add(t.refreshTTL).Unix(),
 		"type":    "refresh",
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(t.secret)
 }
 
-func ParseRefreshToken(tokenStr string) (uint, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func (t *Tokens) ParseRefreshToken(tokenStr string) (uint, error) {
+	token, err := jwt.Parse(tokenStr, t.key)
 	if err != nil || !token.Valid {
 		return 0, errors.New("invalid token")
 	}
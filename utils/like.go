@@ -0,0 +1,11 @@
+package utils
+
+import "strings"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ContainsPattern — шаблон LIKE для поиска подстроки s. Символы %, _ и \ в s
+// экранируются, поэтому в запросе нужен ESCAPE '\'.
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
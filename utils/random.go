@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RandomToken возвращает случайную hex-строку из n байт.
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
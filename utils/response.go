@@ -19,9 +19,10 @@ func RespondCreated(c *gin.Context, data interface{}) {
 	})
 }
 
-func RespondError(c *gin.Context, status int, err interface{}) {
-	c.JSON(status, gin.H{
-		"success": false,
-		"error":   err,
+// RespondAccepted — запрос принят, результат будет готов позже.
+func RespondAccepted(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    data,
 	})
 }